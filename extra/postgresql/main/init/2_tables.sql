@@ -1,13 +0,0 @@
-\c proxybase
-
-CREATE TABLE Request (
-  id SERIAL PRIMARY KEY,
-  method text NOT NULL,
-  scheme text NOT NULL,
-	address text NOT NULL,
-	header text default '',
-	body text default '',
-	userLogin text default '',
-	userPassword text default '',
-	add TIMESTAMPTZ default now()
-);
\ No newline at end of file
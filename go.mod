@@ -1,10 +1,13 @@
 module github.com/SmartPhoneJava/SecurityProxyServer
 
-go 1.12
+go 1.20
 
 require (
+	github.com/andybalholm/brotli v1.0.6
 	github.com/gorilla/mux v1.7.3
 	github.com/jmoiron/sqlx v1.2.0
 	github.com/lib/pq v1.2.0
+	go.etcd.io/bbolt v1.3.8
+	golang.org/x/sys v0.7.0 // indirect
 	google.golang.org/appengine v1.6.3 // indirect
 )
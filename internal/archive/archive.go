@@ -0,0 +1,65 @@
+// Package archive moves the whole project between instances as one
+// gzip compressed tar file. The archive has manifest.json first,
+// then requests/<id>.json with responses and websocket messages
+// of every request in order of ids, rules.json with rules of the
+// project and intercept.json with its intercept settings at the end.
+// What the archive does not keep is listed in the manifest
+package archive
+
+import (
+	"time"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+// Version - version of archive format
+const Version = 1
+
+// names of files in archive
+const (
+	manifestFile  = "manifest.json"
+	requestsDir   = "requests/"
+	rulesFile     = "rules.json"
+	interceptFile = "intercept.json"
+	fileMode      = 0644
+	exportPage    = 500
+	maxRecordSize = 256 << 20
+)
+
+// Omitted - what is not kept in archive: held messages of intercept
+// queue wait for the client, that is gone after export, notes and
+// scope are not supported by the service
+var Omitted = []string{"intercept_queue", "notes", "scope"}
+
+// Manifest - what the archive has
+//easyjson:json
+type Manifest struct {
+	Version  int              `json:"version"`
+	Project  models.ProjectDB `json:"project"`
+	Exported time.Time        `json:"exported"`
+	// Omitted - parts of project, that are not in archive
+	Omitted []string `json:"omitted"`
+}
+
+// Entry - request of history with its response and websocket
+// messages. Credentials are kept apart as they are not sent
+// in json of request
+//easyjson:json
+type Entry struct {
+	Request  models.RequestDB          `json:"request"`
+	Login    string                    `json:"login,omitempty"`
+	Password string                    `json:"password,omitempty"`
+	Messages []models.WebSocketMessage `json:"messages,omitempty"`
+}
+
+// Summary - what was imported
+//easyjson:json
+type Summary struct {
+	Project   models.ProjectDB `json:"project"`
+	Requests  int              `json:"requests"`
+	Responses int              `json:"responses"`
+	Messages  int              `json:"messages"`
+	Rules     int              `json:"rules"`
+	// Intercept - intercept settings are imported
+	Intercept bool `json:"intercept"`
+}
@@ -0,0 +1,123 @@
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/database"
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+// Export write project with all its traffic, rules and intercept
+// settings to w
+func Export(w io.Writer, db database.Store, project *models.ProjectDB) error {
+	var (
+		zw = gzip.NewWriter(w)
+		tw = tar.NewWriter(zw)
+	)
+	err := writeJSON(tw, manifestFile, &Manifest{
+		Version:  Version,
+		Project:  *project,
+		Exported: time.Now(),
+		Omitted:  Omitted,
+	})
+	if err == nil {
+		err = writeRequests(tw, db, project.ID)
+	}
+	if err == nil {
+		var rules *models.RulesDB
+		if rules, err = db.GetRules(project.ID); err == nil {
+			err = writeJSON(tw, rulesFile, rules)
+		}
+	}
+	if err == nil {
+		var settings *models.InterceptSettings
+		if settings, err = db.GetInterceptSettings(project.ID); err == nil {
+			err = writeJSON(tw, interceptFile, settings)
+		}
+	}
+	if err == nil {
+		err = tw.Close()
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	return err
+}
+
+// writeRequests write requests of project going through all pages
+func writeRequests(tw *tar.Writer, db database.Store, projectID int) error {
+	query := models.HistoryQuery{Limit: exportPage, Project: projectID}
+	for {
+		requestsDB, err := db.GetRequests(query)
+		if err != nil {
+			return err
+		}
+		ids := make([]int, 0, len(requestsDB.Requests))
+		for _, request := range requestsDB.Requests {
+			ids = append(ids, request.ID)
+		}
+		responses, err := db.GetResponses(ids)
+		if err != nil {
+			return err
+		}
+		for _, request := range requestsDB.Requests {
+			entry, err := entryOf(db, request, responses[request.ID])
+			if err != nil {
+				return err
+			}
+			name := fmt.Sprintf("%s%09d.json", requestsDir, request.ID)
+			if err = writeJSON(tw, name, entry); err != nil {
+				return err
+			}
+		}
+		if requestsDB.Next == nil {
+			return nil
+		}
+		query.AfterID = *requestsDB.Next
+	}
+}
+
+// entryOf add response, messages and credentials to request.
+// Messages are only loaded for websocket connections
+func entryOf(db database.Store, request models.RequestDB, response *models.ResponseDB) (*Entry, error) {
+	var entry = &Entry{
+		Request:  request,
+		Login:    request.UserLogin,
+		Password: request.UserPassword,
+	}
+	entry.Request.Response = response
+	if response == nil || response.Status != http.StatusSwitchingProtocols {
+		return entry, nil
+	}
+	messages, err := db.GetWebSocketMessages(int32(request.ID))
+	if err != nil {
+		return nil, err
+	}
+	entry.Messages = messages.Messages
+	return entry, nil
+}
+
+// writeJSON write value as json file
+func writeJSON(tw *tar.Writer, name string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	err = tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    fileMode,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = tw.Write(data)
+	return err
+}
@@ -0,0 +1,192 @@
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/database"
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+// Options - how archive is imported
+type Options struct {
+	// Name - name of the new project instead of the name from archive
+	Name string
+	// SkipRules - do not add rules of archive to the new project
+	SkipRules bool
+}
+
+// importer - state of import of one archive
+type importer struct {
+	db      database.Store
+	summary *Summary
+	// ids - new ids of requests by their ids in archive
+	ids map[int]int
+}
+
+// Import make new project from archive in r. The project is not
+// active. If archive can not be imported, the project is removed
+func Import(r io.Reader, db database.Store, options Options) (*Summary, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, errors.New("archive must be gzip compressed tar: " + err.Error())
+	}
+	tr := tar.NewReader(zr)
+
+	var manifest Manifest
+	header, err := tr.Next()
+	if err == nil && header.Name != manifestFile {
+		err = errors.New(manifestFile + " must be the first file")
+	}
+	if err == nil {
+		err = readJSON(tr, header, &manifest)
+	}
+	if err != nil {
+		return nil, errors.New("invalid archive: " + err.Error())
+	}
+	if manifest.Version > Version {
+		return nil, fmt.Errorf("archive of version %d is not supported, update the service", manifest.Version)
+	}
+
+	project := manifest.Project
+	project.ID, project.Active = 0, false
+	if options.Name != "" {
+		project.Name = options.Name
+	}
+	if strings.TrimSpace(project.Name) == "" {
+		project.Name = "imported"
+	}
+	if err = db.CreateProject(&project); err != nil {
+		return nil, err
+	}
+
+	var imp = &importer{
+		db:      db,
+		summary: &Summary{Project: project},
+		ids:     make(map[int]int),
+	}
+	if err = imp.read(tr, options); err != nil {
+		if deleteErr := db.DeleteProject(int32(project.ID)); deleteErr != nil {
+			err = fmt.Errorf("%v, project %d is not removed: %v", err, project.ID, deleteErr)
+		}
+		return nil, err
+	}
+	return imp.summary, nil
+}
+
+// read import files of archive after manifest
+func (imp *importer) read(tr *tar.Reader, options Options) error {
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.New("invalid archive: " + err.Error())
+		}
+		switch {
+		case strings.HasPrefix(header.Name, requestsDir):
+			var entry Entry
+			if err = readJSON(tr, header, &entry); err == nil {
+				err = imp.request(&entry)
+			}
+		case header.Name == rulesFile && !options.SkipRules:
+			var rules models.RulesDB
+			if err = readJSON(tr, header, &rules); err == nil {
+				err = imp.rules(rules.Rules)
+			}
+		case header.Name == interceptFile:
+			var settings models.InterceptSettings
+			if err = readJSON(tr, header, &settings); err == nil {
+				err = imp.intercept(&settings)
+			}
+		}
+		// files of newer versions are skipped
+		if err != nil {
+			return fmt.Errorf("%s: %v", header.Name, err)
+		}
+	}
+}
+
+// request save request of entry with its response and messages
+// to the new project
+func (imp *importer) request(entry *Entry) error {
+	var (
+		rdb      = entry.Request
+		oldID    = rdb.ID
+		response = rdb.Response
+	)
+	rdb.ID, rdb.Response = 0, nil
+	rdb.ProjectID = imp.summary.Project.ID
+	rdb.UserLogin, rdb.UserPassword = entry.Login, entry.Password
+	if rdb.Parent != nil {
+		// parent of other project or removed one is lost
+		if parent, ok := imp.ids[*rdb.Parent]; ok {
+			rdb.Parent = &parent
+		} else {
+			rdb.Parent = nil
+		}
+	}
+	if err := imp.db.CreateRequest(&rdb); err != nil {
+		return err
+	}
+	imp.ids[oldID] = rdb.ID
+	imp.summary.Requests++
+	if len(rdb.Tags) > 0 {
+		if err := imp.db.SetRequestTags(int32(rdb.ID), rdb.Tags); err != nil {
+			return err
+		}
+	}
+
+	if response != nil {
+		response.ID, response.RequestID = 0, rdb.ID
+		if err := imp.db.CreateResponse(response); err != nil {
+			return err
+		}
+		imp.summary.Responses++
+	}
+	for _, message := range entry.Messages {
+		message.ID, message.RequestID = 0, rdb.ID
+		if err := imp.db.CreateWebSocketMessage(&message); err != nil {
+			return err
+		}
+		imp.summary.Messages++
+	}
+	return nil
+}
+
+// rules add rules of archive as new ones of the new project
+func (imp *importer) rules(rules []models.RuleDB) error {
+	for _, rule := range rules {
+		rule.ID, rule.ProjectID = 0, imp.summary.Project.ID
+		if err := imp.db.CreateRule(&rule); err != nil {
+			return err
+		}
+		imp.summary.Rules++
+	}
+	return nil
+}
+
+// intercept set intercept settings of archive to the new project.
+// They take effect when the project is activated
+func (imp *importer) intercept(settings *models.InterceptSettings) error {
+	settings.ProjectID = imp.summary.Project.ID
+	if err := imp.db.UpdateInterceptSettings(settings); err != nil {
+		return err
+	}
+	imp.summary.Intercept = true
+	return nil
+}
+
+// readJSON decode json file of archive
+func readJSON(tr *tar.Reader, header *tar.Header, value interface{}) error {
+	if header.Size > maxRecordSize {
+		return errors.New("file is too large")
+	}
+	return json.NewDecoder(tr).Decode(value)
+}
@@ -0,0 +1,777 @@
+package database
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"time"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+// buckets of bolt store. Records are kept by id, indexes
+// have keys of request id and record id without values
+var (
+	requestsBucket     = []byte("requests")
+	responsesBucket    = []byte("responses")
+	responsesIndex     = []byte("responses_by_request")
+	webSocketBucket    = []byte("websocket")
+	webSocketIndex     = []byte("websocket_by_request")
+	settingsBucket     = []byte("settings")
+	interceptBucket    = []byte("intercept")
+	rulesBucket        = []byte("rules")
+	projectsBucket     = []byte("projects")
+	interceptSettingID = []byte("intercept")
+)
+
+// historyBuckets - buckets of requests with their responses and messages
+var historyBuckets = [][]byte{requestsBucket, responsesBucket, responsesIndex, webSocketBucket, webSocketIndex}
+
+// BoltDB - store in one file, that needs no database server.
+// The file can be opened by one process only
+type BoltDB struct{ db *bolt.DB }
+
+// OpenBolt open store in file path, creating it if needed
+func OpenBolt(path string) (*BoltDB, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err == bolt.ErrTimeout {
+		return nil, errors.New("store " + path + " is used by another process")
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range append(historyBuckets, settingsBucket, interceptBucket, rulesBucket, projectsBucket) {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return createDefaultProject(tx.Bucket(projectsBucket))
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltDB{db}, nil
+}
+
+func (db *BoltDB) Close() error {
+	return db.db.Close()
+}
+
+// CreateRequest add request to store
+func (db *BoltDB) CreateRequest(rdb *models.RequestDB) error {
+	rdb.Search = rdb.SearchText()
+	if rdb.Add.IsZero() {
+		rdb.Add = time.Now()
+	}
+	if rdb.Tags == nil {
+		rdb.Tags = models.Tags{}
+	}
+	if rdb.ProjectID == 0 {
+		rdb.ProjectID = models.DefaultProject
+	}
+	return db.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(projectsBucket).Get(key(rdb.ProjectID)) == nil {
+			return errors.New("no project with such id")
+		}
+		bucket := tx.Bucket(requestsBucket)
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		rdb.ID = int(id)
+		// response is stored separately
+		stored := *rdb
+		stored.Response = nil
+		return put(bucket, rdb.ID, &stored)
+	})
+}
+
+// GetRequests return page of requests of history matching query
+// with cursor of the next page and total number of matching requests
+func (db *BoltDB) GetRequests(query models.HistoryQuery) (*models.RequestsDB, error) {
+	page := &historyPage{query: query}
+	err := db.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(requestsBucket).Cursor()
+		first, next := cursor.First, cursor.Next
+		if query.Desc {
+			first, next = cursor.Last, cursor.Prev
+		}
+		for key, value := first(); key != nil; key, value = next() {
+			var request models.RequestDB
+			if err := decodeRequest(value, &request); err != nil {
+				return err
+			}
+			responses, err := responsesOf(tx, request.ID)
+			if err != nil {
+				return err
+			}
+			page.add(&request, responses)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return page.result(), nil
+}
+
+func (db *BoltDB) GetRequest(id int32) (*models.RequestDB, error) {
+	requestDB := &models.RequestDB{}
+	err := db.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(requestsBucket).Get(key(int(id)))
+		if value == nil {
+			return sql.ErrNoRows
+		}
+		return decodeRequest(value, requestDB)
+	})
+	return requestDB, err
+}
+
+// SetRequestTags replace tags of request with id
+func (db *BoltDB) SetRequestTags(id int32, tags models.Tags) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(requestsBucket)
+		value := bucket.Get(key(int(id)))
+		if value == nil {
+			return sql.ErrNoRows
+		}
+		var request models.RequestDB
+		if err := decodeRequest(value, &request); err != nil {
+			return err
+		}
+		request.Tags = tags
+		return put(bucket, request.ID, &request)
+	})
+}
+
+// DeleteRequest remove request with id with its responses and messages
+func (db *BoltDB) DeleteRequest(id int32) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		return deleteRequest(tx, int(id))
+	})
+}
+
+// DeleteRequests remove requests of project with projectID
+// with their responses and messages. Ids are not reused
+func (db *BoltDB) DeleteRequests(projectID int) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		return deleteRequests(tx, projectID)
+	})
+}
+
+// deleteRequests remove requests of project with projectID
+// with their responses and messages
+func deleteRequests(tx *bolt.Tx, projectID int) error {
+	var (
+		requests = tx.Bucket(requestsBucket)
+		ids      []int
+	)
+	err := requests.ForEach(func(_, value []byte) error {
+		var request models.RequestDB
+		if err := decodeRequest(value, &request); err != nil {
+			return err
+		}
+		if request.ProjectID == projectID {
+			ids = append(ids, request.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// records can not be deleted while bucket is iterated
+	for _, id := range ids {
+		if err = deleteRequest(tx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteRequest remove request with id with its responses and messages
+func deleteRequest(tx *bolt.Tx, id int) error {
+	if err := deleteIndexed(tx, responsesBucket, responsesIndex, id); err != nil {
+		return err
+	}
+	if err := deleteIndexed(tx, webSocketBucket, webSocketIndex, id); err != nil {
+		return err
+	}
+	return tx.Bucket(requestsBucket).Delete(key(id))
+}
+
+// CreateResponse add response to store
+func (db *BoltDB) CreateResponse(resp *models.ResponseDB) error {
+	resp.Search = resp.SearchText()
+	if resp.Add.IsZero() {
+		resp.Add = time.Now()
+	}
+	return db.db.Update(func(tx *bolt.Tx) error {
+		id, err := createIndexed(tx, responsesBucket, responsesIndex, resp.RequestID)
+		if err != nil {
+			return err
+		}
+		resp.ID = id
+		return put(tx.Bucket(responsesBucket), id, resp)
+	})
+}
+
+// GetResponse return response to the request with id requestID
+func (db *BoltDB) GetResponse(requestID int32) (*models.ResponseDB, error) {
+	responseDB := &models.ResponseDB{}
+	err := db.db.View(func(tx *bolt.Tx) error {
+		responses, err := responsesOf(tx, int(requestID))
+		if err != nil {
+			return err
+		}
+		if len(responses) == 0 {
+			return sql.ErrNoRows
+		}
+		*responseDB = responses[len(responses)-1]
+		return nil
+	})
+	return responseDB, err
+}
+
+// GetResponses return the latest responses to requests with ids
+// by the id of request
+func (db *BoltDB) GetResponses(requestIDs []int) (map[int]*models.ResponseDB, error) {
+	responses := make(map[int]*models.ResponseDB, len(requestIDs))
+	err := db.db.View(func(tx *bolt.Tx) error {
+		for _, id := range requestIDs {
+			found, err := responsesOf(tx, id)
+			if err != nil {
+				return err
+			}
+			if len(found) > 0 {
+				responses[id] = &found[len(found)-1]
+			}
+		}
+		return nil
+	})
+	return responses, err
+}
+
+// CreateWebSocketMessage add websocket message to store
+func (db *BoltDB) CreateWebSocketMessage(message *models.WebSocketMessage) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		id, err := createIndexed(tx, webSocketBucket, webSocketIndex, message.RequestID)
+		if err != nil {
+			return err
+		}
+		message.ID = id
+		return put(tx.Bucket(webSocketBucket), id, message)
+	})
+}
+
+// GetWebSocketMessages return messages of websocket connection,
+// opened by the request with id requestID
+func (db *BoltDB) GetWebSocketMessages(requestID int32) (*models.WebSocketMessages, error) {
+	messages := make([]models.WebSocketMessage, 0)
+	err := db.db.View(func(tx *bolt.Tx) error {
+		return eachIndexed(tx, webSocketBucket, webSocketIndex, int(requestID), func(value []byte) error {
+			var message models.WebSocketMessage
+			if err := decode(value, &message); err != nil {
+				return err
+			}
+			messages = append(messages, message)
+			return nil
+		})
+	})
+	return &models.WebSocketMessages{Messages: messages}, err
+}
+
+// interceptSettingsKey return key of intercept settings of project
+// with projectID
+func interceptSettingsKey(projectID int) []byte {
+	return append(append([]byte{}, interceptSettingID...), key(projectID)...)
+}
+
+// GetInterceptSettings return current settings of intercept mode
+// of project with projectID
+func (db *BoltDB) GetInterceptSettings(projectID int) (*models.InterceptSettings, error) {
+	settings := &models.InterceptSettings{}
+	err := db.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(settingsBucket).Get(interceptSettingsKey(projectID))
+		if value == nil {
+			// intercept mode is off until it is set
+			return nil
+		}
+		return decode(value, settings)
+	})
+	settings.ProjectID = projectID
+	return settings, err
+}
+
+// UpdateInterceptSettings replace settings of intercept mode
+// of project of settings
+func (db *BoltDB) UpdateInterceptSettings(settings *models.InterceptSettings) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		value, err := encode(settings)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(settingsBucket).Put(interceptSettingsKey(settings.ProjectID), value)
+	})
+}
+
+// CreateInterceptItem add held message to the queue of its project
+func (db *BoltDB) CreateInterceptItem(item *models.InterceptItem) error {
+	if item.ProjectID == 0 {
+		item.ProjectID = models.DefaultProject
+	}
+	if item.State == "" {
+		item.State = models.InterceptPending
+	}
+	if item.Add.IsZero() {
+		item.Add = time.Now()
+	}
+	return db.db.Update(func(tx *bolt.Tx) error {
+		return create(tx.Bucket(interceptBucket), &item.ID, item)
+	})
+}
+
+// GetInterceptItem return held message with id
+func (db *BoltDB) GetInterceptItem(id int32) (*models.InterceptItem, error) {
+	item := &models.InterceptItem{}
+	err := db.db.View(func(tx *bolt.Tx) error {
+		return get(tx.Bucket(interceptBucket), int(id), item)
+	})
+	return item, err
+}
+
+// GetInterceptItems return messages of project with projectID
+// waiting for operator, older first
+func (db *BoltDB) GetInterceptItems(projectID int) (*models.InterceptItems, error) {
+	items := make([]models.InterceptItem, 0)
+	err := db.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(interceptBucket).ForEach(func(_, value []byte) error {
+			var item models.InterceptItem
+			if err := decode(value, &item); err != nil {
+				return err
+			}
+			if item.State == models.InterceptPending && item.ProjectID == projectID {
+				items = append(items, item)
+			}
+			return nil
+		})
+	})
+	return &models.InterceptItems{Items: items}, err
+}
+
+// DecideInterceptItem set state of pending message. Modified can be
+// empty to forward message as it is. sql.ErrNoRows is returned if
+// there is no such pending message
+func (db *BoltDB) DecideInterceptItem(id int32, state, modified string) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		var (
+			bucket = tx.Bucket(interceptBucket)
+			item   models.InterceptItem
+		)
+		if err := get(bucket, int(id), &item); err != nil {
+			return err
+		}
+		if item.State != models.InterceptPending {
+			return sql.ErrNoRows
+		}
+		item.State, item.Modified = state, modified
+		return put(bucket, item.ID, &item)
+	})
+}
+
+// DeleteInterceptItem remove message from the queue
+func (db *BoltDB) DeleteInterceptItem(id int32) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(interceptBucket).Delete(key(int(id)))
+	})
+}
+
+// CreateRule add match and replace rule of its project to store
+func (db *BoltDB) CreateRule(rule *models.RuleDB) error {
+	if rule.ProjectID == 0 {
+		rule.ProjectID = models.DefaultProject
+	}
+	if rule.Add.IsZero() {
+		rule.Add = time.Now()
+	}
+	return db.db.Update(func(tx *bolt.Tx) error {
+		return create(tx.Bucket(rulesBucket), &rule.ID, rule)
+	})
+}
+
+// UpdateRule replace rule with the same id, project of rule is not
+// changed. sql.ErrNoRows is returned if there is no such rule
+func (db *BoltDB) UpdateRule(rule *models.RuleDB) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		var (
+			bucket = tx.Bucket(rulesBucket)
+			old    models.RuleDB
+		)
+		if err := get(bucket, rule.ID, &old); err != nil {
+			return err
+		}
+		rule.Add, rule.ProjectID = old.Add, old.ProjectID
+		return put(bucket, rule.ID, rule)
+	})
+}
+
+// GetRules return rules of project with projectID in order of creation
+func (db *BoltDB) GetRules(projectID int) (*models.RulesDB, error) {
+	rules := make([]models.RuleDB, 0)
+	err := db.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(rulesBucket).ForEach(func(_, value []byte) error {
+			var rule models.RuleDB
+			if err := decode(value, &rule); err != nil {
+				return err
+			}
+			if rule.ProjectID == projectID {
+				rules = append(rules, rule)
+			}
+			return nil
+		})
+	})
+	return &models.RulesDB{Rules: rules}, err
+}
+
+func (db *BoltDB) GetRule(id int32) (*models.RuleDB, error) {
+	rule := &models.RuleDB{}
+	err := db.db.View(func(tx *bolt.Tx) error {
+		return get(tx.Bucket(rulesBucket), int(id), rule)
+	})
+	return rule, err
+}
+
+// DeleteRule remove rule with id. sql.ErrNoRows is returned if
+// there is no such rule
+func (db *BoltDB) DeleteRule(id int32) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(rulesBucket)
+		if bucket.Get(key(int(id))) == nil {
+			return sql.ErrNoRows
+		}
+		return bucket.Delete(key(int(id)))
+	})
+}
+
+// createDefaultProject add active default project to the new store
+func createDefaultProject(bucket *bolt.Bucket) error {
+	if bucket.Sequence() > 0 {
+		return nil
+	}
+	project := &models.ProjectDB{Name: "default", Active: true, Add: time.Now()}
+	return create(bucket, &project.ID, project)
+}
+
+// CreateProject add project to store. New project is not active
+func (db *BoltDB) CreateProject(project *models.ProjectDB) error {
+	project.Active = false
+	if project.Add.IsZero() {
+		project.Add = time.Now()
+	}
+	return db.db.Update(func(tx *bolt.Tx) error {
+		return create(tx.Bucket(projectsBucket), &project.ID, project)
+	})
+}
+
+// GetProjects return all projects in order of creation
+func (db *BoltDB) GetProjects() (*models.ProjectsDB, error) {
+	projects := make([]models.ProjectDB, 0)
+	err := db.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(projectsBucket).ForEach(func(_, value []byte) error {
+			var project models.ProjectDB
+			if err := decode(value, &project); err != nil {
+				return err
+			}
+			projects = append(projects, project)
+			return nil
+		})
+	})
+	return &models.ProjectsDB{Projects: projects}, err
+}
+
+func (db *BoltDB) GetProject(id int32) (*models.ProjectDB, error) {
+	project := &models.ProjectDB{}
+	err := db.db.View(func(tx *bolt.Tx) error {
+		return get(tx.Bucket(projectsBucket), int(id), project)
+	})
+	return project, err
+}
+
+// GetActiveProject return project, the proxy records to
+func (db *BoltDB) GetActiveProject() (*models.ProjectDB, error) {
+	projects, err := db.GetProjects()
+	if err != nil {
+		return nil, err
+	}
+	for _, project := range projects.Projects {
+		if project.Active {
+			return &project, nil
+		}
+	}
+	return &models.ProjectDB{}, sql.ErrNoRows
+}
+
+// UpdateProject change name and archived flag of project with
+// the same id. sql.ErrNoRows is returned if there is no such project
+func (db *BoltDB) UpdateProject(project *models.ProjectDB) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		var (
+			bucket = tx.Bucket(projectsBucket)
+			old    models.ProjectDB
+		)
+		if err := get(bucket, project.ID, &old); err != nil {
+			return err
+		}
+		old.Name, old.Archived = project.Name, project.Archived
+		*project = old
+		return put(bucket, project.ID, project)
+	})
+}
+
+// ActivateProject make project with id the only active one.
+// sql.ErrNoRows is returned if there is no such project
+// or it is archived
+func (db *BoltDB) ActivateProject(id int32) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		var (
+			bucket   = tx.Bucket(projectsBucket)
+			projects []models.ProjectDB
+			found    bool
+		)
+		err := bucket.ForEach(func(_, value []byte) error {
+			var project models.ProjectDB
+			if err := decode(value, &project); err != nil {
+				return err
+			}
+			if project.ID == int(id) && !project.Archived {
+				found = true
+			}
+			projects = append(projects, project)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if !found {
+			return sql.ErrNoRows
+		}
+		for _, project := range projects {
+			project.Active = project.ID == int(id)
+			if err = put(bucket, project.ID, &project); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteProject remove project with id with all its traffic, rules
+// and intercepted messages. sql.ErrNoRows is returned if there is no
+// such project, ErrDefaultProject for default project
+func (db *BoltDB) DeleteProject(id int32) error {
+	if id == models.DefaultProject {
+		return ErrDefaultProject
+	}
+	return db.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(projectsBucket)
+		if bucket.Get(key(int(id))) == nil {
+			return sql.ErrNoRows
+		}
+		if err := deleteRequests(tx, int(id)); err != nil {
+			return err
+		}
+		if err := deleteRules(tx, int(id)); err != nil {
+			return err
+		}
+		if err := deleteInterceptItems(tx, int(id)); err != nil {
+			return err
+		}
+		if err := tx.Bucket(settingsBucket).Delete(interceptSettingsKey(int(id))); err != nil {
+			return err
+		}
+		return bucket.Delete(key(int(id)))
+	})
+}
+
+// deleteRules remove rules of project with projectID
+func deleteRules(tx *bolt.Tx, projectID int) error {
+	var (
+		bucket = tx.Bucket(rulesBucket)
+		keys   [][]byte
+	)
+	err := bucket.ForEach(func(k, value []byte) error {
+		var rule models.RuleDB
+		if err := decode(value, &rule); err != nil {
+			return err
+		}
+		if rule.ProjectID == projectID {
+			keys = append(keys, k)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return deleteKeys(bucket, keys)
+}
+
+// deleteInterceptItems remove held messages of project with projectID
+func deleteInterceptItems(tx *bolt.Tx, projectID int) error {
+	var (
+		bucket = tx.Bucket(interceptBucket)
+		keys   [][]byte
+	)
+	err := bucket.ForEach(func(k, value []byte) error {
+		var item models.InterceptItem
+		if err := decode(value, &item); err != nil {
+			return err
+		}
+		if item.ProjectID == projectID {
+			keys = append(keys, k)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return deleteKeys(bucket, keys)
+}
+
+// deleteKeys remove records with keys. Keys are collected before
+// removal as bucket can't be changed in ForEach
+func deleteKeys(bucket *bolt.Bucket, keys [][]byte) error {
+	for _, k := range keys {
+		if err := bucket.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// key make key of record with id. Big endian keeps order of ids
+func key(id int) []byte {
+	var b = make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(id))
+	return b
+}
+
+func encode(value interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	err := gob.NewEncoder(&buffer).Encode(value)
+	return buffer.Bytes(), err
+}
+
+func decode(data []byte, value interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(value)
+}
+
+// decodeRequest decode request with empty body and tags
+// instead of nil, as database returns them
+func decodeRequest(data []byte, request *models.RequestDB) error {
+	if err := decode(data, request); err != nil {
+		return err
+	}
+	if request.Body == nil {
+		request.Body = []byte{}
+	}
+	if request.Tags == nil {
+		request.Tags = models.Tags{}
+	}
+	return nil
+}
+
+func put(bucket *bolt.Bucket, id int, value interface{}) error {
+	data, err := encode(value)
+	if err != nil {
+		return err
+	}
+	return bucket.Put(key(id), data)
+}
+
+func get(bucket *bolt.Bucket, id int, value interface{}) error {
+	data := bucket.Get(key(id))
+	if data == nil {
+		return sql.ErrNoRows
+	}
+	return decode(data, value)
+}
+
+// create put value with the next id, that is set to id
+func create(bucket *bolt.Bucket, id *int, value interface{}) error {
+	sequence, err := bucket.NextSequence()
+	if err != nil {
+		return err
+	}
+	*id = int(sequence)
+	return put(bucket, *id, value)
+}
+
+// createIndexed return the next id of record of request with
+// requestID and add it to index. Request must exist
+func createIndexed(tx *bolt.Tx, name, index []byte, requestID int) (int, error) {
+	if tx.Bucket(requestsBucket).Get(key(requestID)) == nil {
+		return 0, errors.New("no request with such id")
+	}
+	sequence, err := tx.Bucket(name).NextSequence()
+	if err != nil {
+		return 0, err
+	}
+	id := int(sequence)
+	return id, tx.Bucket(index).Put(append(key(requestID), key(id)...), nil)
+}
+
+// eachIndexed call f for records of request with requestID in order of ids
+func eachIndexed(tx *bolt.Tx, name, index []byte, requestID int, f func(value []byte) error) error {
+	var (
+		records = tx.Bucket(name)
+		cursor  = tx.Bucket(index).Cursor()
+		prefix  = key(requestID)
+	)
+	for k, _ := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = cursor.Next() {
+		if value := records.Get(k[len(prefix):]); value != nil {
+			if err := f(value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// deleteIndexed remove records of request with requestID and their index
+func deleteIndexed(tx *bolt.Tx, name, index []byte, requestID int) error {
+	var (
+		records = tx.Bucket(name)
+		keys    [][]byte
+		cursor  = tx.Bucket(index).Cursor()
+		prefix  = key(requestID)
+	)
+	for k, _ := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = cursor.Next() {
+		keys = append(keys, append([]byte{}, k...))
+	}
+	for _, k := range keys {
+		if err := records.Delete(k[len(prefix):]); err != nil {
+			return err
+		}
+		if err := tx.Bucket(index).Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func responsesOf(tx *bolt.Tx, requestID int) ([]models.ResponseDB, error) {
+	var responses []models.ResponseDB
+	err := eachIndexed(tx, responsesBucket, responsesIndex, requestID, func(value []byte) error {
+		var response models.ResponseDB
+		if err := decode(value, &response); err != nil {
+			return err
+		}
+		responses = append(responses, response)
+		return nil
+	})
+	return responses, err
+}
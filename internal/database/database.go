@@ -1,17 +1,16 @@
 package database
 
 import (
-	"fmt"
-	"strconv"
-	"strings"
+	"database/sql"
 	"time"
 
 	//_ "github.com/jackc/pgx/v4"
 	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
+// DB - store in postgres
 type DB struct{ db *sqlx.DB }
 
 type Settings struct {
@@ -42,31 +41,53 @@ func Init(settings Settings) (*DB, error) {
 }
 
 func (db *DB) Close() error {
-	return db.Close()
+	return db.db.Close()
 }
 
 // CreateRequest add requesat to database
 func (db *DB) CreateRequest(rdb *models.RequestDB) error {
-	rdb.MakeHeaderRAW()
-
-	fmt.Println("method:", rdb.Method)
-	fmt.Println("scheme:", rdb.Scheme)
-	fmt.Println("address:", rdb.RemoteAddr)
-	fmt.Println("header:", rdb.Header)
-	fmt.Println("body:", rdb.Body)
-	fmt.Println("userlogin:", rdb.UserLogin)
-	fmt.Println("userpassword:", rdb.UserPassword)
+	rdb.Search = rdb.SearchText()
+	if rdb.Add.IsZero() {
+		rdb.Add = time.Now()
+	}
+	if rdb.ProjectID == 0 {
+		rdb.ProjectID = models.DefaultProject
+	}
 
 	sqlInsert := `
-	INSERT INTO Request(method, scheme, address, header, body,
-		userlogin, userpassword) VALUES
-		(:method, :scheme, :address, :header, :body,
-			:userlogin, :userpassword)
+	INSERT INTO Request(method, scheme, address, path, query, url, proto,
+		header, body, truncated, userlogin, userpassword, parent, project_id, search, add) VALUES
+		(:method, :scheme, :address, :path, :query, :url, :proto,
+			:header, :body, :truncated, :userlogin, :userpassword, :parent, :project_id, :search, :add)
 			RETURNING *;
 		`
 	return db.createAndReturnStruct(sqlInsert, rdb)
 }
 
+// CreateResponse add response to database
+func (db *DB) CreateResponse(resp *models.ResponseDB) error {
+	resp.Search = resp.SearchText()
+	if resp.Add.IsZero() {
+		resp.Add = time.Now()
+	}
+	sqlInsert := `
+	INSERT INTO Response(request_id, proto, status, header, body, size, truncated, duration, search, add) VALUES
+		(:request_id, :proto, :status, :header, :body, :size, :truncated, :duration, :search, :add)
+			RETURNING *;
+		`
+	return db.createAndReturnStruct(sqlInsert, resp)
+}
+
+// CreateWebSocketMessage add websocket message to database
+func (db *DB) CreateWebSocketMessage(message *models.WebSocketMessage) error {
+	sqlInsert := `
+	INSERT INTO WebSocketMessage(request_id, direction, opcode, payload, add) VALUES
+		(:request_id, :direction, :opcode, :payload, :add)
+			RETURNING *;
+		`
+	return db.createAndReturnStruct(sqlInsert, message)
+}
+
 func (db *DB) createAndReturnStruct(statement string, obj interface{}) error {
 	rows, err := db.db.NamedQuery(statement, obj)
 	if err != nil {
@@ -79,125 +100,152 @@ func (db *DB) createAndReturnStruct(statement string, obj interface{}) error {
 	return err
 }
 
-func applyParameter(statement *string, counter *int, parameterInsert string, valid func() bool) {
-	if valid() {
-		if *counter > 0 {
-			*statement += ` and `
-		} else {
-			*statement += ` where `
-		}
-		*statement += parameterInsert
-		*counter++
+// GetRequests return page of requests of history matching query
+// with cursor of the next page and total number of matching requests
+func (db *DB) GetRequests(query models.HistoryQuery) (*models.RequestsDB, error) {
+	var (
+		builder = &queryBuilder{}
+		where   = builder.where(&query.Filter)
+		total   int
+	)
+	if query.Project > 0 {
+		where += ` and project_id = ` + builder.arg(query.Project)
 	}
-}
-
-func addToQuery(key, value string) string {
-	return " " + key + " = '" + value + "' "
-}
-
-func isInside(origin string, variants ...string) bool {
-	for _, variant := range variants {
-		if origin == variant {
-			return true
-		}
+	if err := db.db.Get(&total, `select count(*) from Request where `+where, builder.args...); err != nil {
+		return nil, err
 	}
-	return false
-}
 
-func applyScheme(statement *string, counter *int, scheme string) {
-	if strings.Contains(scheme, "'") || strings.Contains(scheme, ";") {
-		return
+	statement := `select * from Request where ` + where
+	if query.AfterID > 0 {
+		statement += ` and id > ` + builder.arg(query.AfterID)
 	}
-	applyParameter(statement, counter, addToQuery("scheme", scheme), func() bool {
-		return scheme == "http" || scheme == "https"
-	})
-}
+	if query.BeforeID > 0 {
+		statement += ` and id < ` + builder.arg(query.BeforeID)
+	}
+	if query.Desc {
+		statement += ` order by id desc`
+	} else {
+		statement += ` order by id`
+	}
+	// one more request shows if there is the next page
+	statement += ` limit ` + builder.arg(query.Limit+1)
 
-func applyMethod(statement *string, counter *int, method string) {
-	if strings.Contains(method, "'") || strings.Contains(method, ";") {
-		return
+	rows, err := db.db.Queryx(statement, builder.args...)
+	if err != nil {
+		return nil, err
 	}
-	applyParameter(statement, counter, addToQuery("method", strings.ToUpper(method)), func() bool {
-		var meth = strings.ToLower(method)
-		return isInside(meth, "connect", "post", "get", "put", "options", "delete", "head")
-	})
-}
+	defer rows.Close()
 
-func applyAddress(statement *string, counter *int, address string) {
-	if strings.Contains(address, "'") || strings.Contains(address, ";") {
-		return
+	requests := make([]models.RequestDB, 0)
+	for rows.Next() {
+		var request models.RequestDB
+		if err = rows.StructScan(&request); err != nil {
+			return nil, err
+		}
+		requests = append(requests, request)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
 	}
-	applyParameter(statement, counter, "POSITION (lower('"+address+"') IN lower(address)) > 0", func() bool {
-		return address != ""
-	})
-}
 
-func applyLimit(statement *string, limit string) {
-	if _, err := strconv.Atoi(limit); err != nil {
-		limit = "20"
+	requestsDB := &models.RequestsDB{Total: total}
+	if len(requests) > query.Limit {
+		requests = requests[:query.Limit]
+		next := requests[len(requests)-1].ID
+		requestsDB.Next = &next
 	}
-	*statement += " limit " + limit + " "
+	requestsDB.Requests = requests
+	return requestsDB, nil
 }
 
-func applyDesc(statement *string, last string) {
-	if last == "false" || last == "0" || last == "-" {
-		return
+// SetRequestTags replace tags of request with id
+func (db *DB) SetRequestTags(id int32, tags models.Tags) error {
+	statement := `update Request set tags = $1 where id = $2`
+	result, err := db.db.Exec(statement, tags, id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
 	}
-	*statement += " order by id desc "
+	return err
 }
 
-func (db *DB) GetRequests(scheme, method, limit, last, address string) (*models.RequestsDB, error) {
-
-	var (
-		statement = `select * from Request`
-		rows      *sqlx.Rows
-		err       error
-	)
-
-	var counter = 0
-	applyScheme(&statement, &counter, scheme)
-	applyMethod(&statement, &counter, method)
-	applyAddress(&statement, &counter, address)
-	applyDesc(&statement, last)
-	applyLimit(&statement, limit)
+func (db *DB) GetRequest(id int32) (*models.RequestDB, error) {
+	statement := `select * from Request where id = $1`
+	row := db.db.QueryRowx(statement, id)
+	requestDB := &models.RequestDB{}
+	err := row.StructScan(requestDB)
+	return requestDB, err
+}
 
-	fmt.Println("statement:", statement)
+// GetResponse return response to the request with id requestID
+func (db *DB) GetResponse(requestID int32) (*models.ResponseDB, error) {
+	statement := `select * from Response where request_id = $1 order by id desc limit 1`
+	row := db.db.QueryRowx(statement, requestID)
+	responseDB := &models.ResponseDB{}
+	err := row.StructScan(responseDB)
+	return responseDB, err
+}
 
-	rows, err = db.db.Queryx(statement)
+// GetResponses return the latest responses to requests with ids
+// by the id of request
+func (db *DB) GetResponses(requestIDs []int) (map[int]*models.ResponseDB, error) {
+	statement := `select distinct on (request_id) * from Response
+		where request_id = any($1) order by request_id, id desc`
+	ids := make([]int64, 0, len(requestIDs))
+	for _, id := range requestIDs {
+		ids = append(ids, int64(id))
+	}
+	rows, err := db.db.Queryx(statement, pq.Array(ids))
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	requests := make([]models.RequestDB, 0)
-
+	responses := make(map[int]*models.ResponseDB, len(requestIDs))
 	for rows.Next() {
-		var request models.RequestDB
-		err = rows.StructScan(&request)
-		if err != nil {
-			break
+		var response = &models.ResponseDB{}
+		if err = rows.StructScan(response); err != nil {
+			return nil, err
 		}
-		requests = append(requests, request)
+		responses[response.RequestID] = response
 	}
+	return responses, rows.Err()
+}
+
+// GetWebSocketMessages return messages of websocket connection,
+// opened by the request with id requestID
+func (db *DB) GetWebSocketMessages(requestID int32) (*models.WebSocketMessages, error) {
+	statement := `select * from WebSocketMessage where request_id = $1 order by id`
+	rows, err := db.db.Queryx(statement, requestID)
 	if err != nil {
 		return nil, err
 	}
-	requestsDB := &models.RequestsDB{
-		Requests: requests,
+	defer rows.Close()
+
+	messages := make([]models.WebSocketMessage, 0)
+	for rows.Next() {
+		var message models.WebSocketMessage
+		if err = rows.StructScan(&message); err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
 	}
-	return requestsDB, err
+	return &models.WebSocketMessages{Messages: messages}, rows.Err()
 }
 
-func (db *DB) GetRequest(id int32) (*models.RequestDB, error) {
-	statement := `select * from Request where id = $1`
-	row := db.db.QueryRowx(statement, id)
-	requestDB := &models.RequestDB{}
-	err := row.StructScan(requestDB)
-	return requestDB, err
+// DeleteRequest remove request with id with its responses and messages
+func (db *DB) DeleteRequest(id int32) error {
+	statement := `delete from Request where id = $1`
+	_, err := db.db.Exec(statement, id)
+	return err
 }
 
-func (db *DB) DeleteRequests() error {
-	statement := `delete from Request`
-	_, err := db.db.Exec(statement)
+// DeleteRequests remove requests of project with projectID
+// with their responses and messages
+func (db *DB) DeleteRequests(projectID int) error {
+	statement := `delete from Request where project_id = $1`
+	_, err := db.db.Exec(statement, projectID)
 	return err
 }
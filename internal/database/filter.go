@@ -0,0 +1,118 @@
+package database
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+// requestHost - host of request without port
+const requestHost = `regexp_replace(lower(address), ':[0-9]+$', '')`
+
+// queryBuilder collect arguments of parameterized statement
+type queryBuilder struct {
+	args []interface{}
+}
+
+// arg add argument and return its placeholder
+func (builder *queryBuilder) arg(value interface{}) string {
+	builder.args = append(builder.args, value)
+	return "$" + strconv.Itoa(len(builder.args))
+}
+
+// where compile filter to condition on Request table.
+// Empty filter gives "true"
+func (builder *queryBuilder) where(filter *models.HistoryFilter) string {
+	var conditions []string
+	add := func(condition string) {
+		conditions = append(conditions, condition)
+	}
+
+	if filter.Scheme != "" {
+		add(`scheme = ` + builder.arg(filter.Scheme))
+	}
+	if filter.Host != "" {
+		host := strings.ToLower(filter.Host)
+		switch filter.HostMatch {
+		case models.HostExact:
+			add(requestHost + ` = ` + builder.arg(host))
+		case models.HostSuffix:
+			add(`(` + requestHost + ` = ` + builder.arg(host) + ` or ` +
+				requestHost + ` like ` + builder.arg("%."+escapeLike(host)) + `)`)
+		default:
+			add(`position(` + builder.arg(host) + ` in lower(address)) > 0`)
+		}
+	}
+	if filter.PathPrefix != "" {
+		add(`path like ` + builder.arg(escapeLike(filter.PathPrefix)+"%"))
+	}
+	if len(filter.Methods) > 0 {
+		var methods []string
+		for _, method := range filter.Methods {
+			methods = append(methods, builder.arg(strings.ToUpper(method)))
+		}
+		add(`method in (` + strings.Join(methods, ", ") + `)`)
+	}
+	if len(filter.Status) > 0 {
+		var ranges []string
+		for _, status := range filter.Status {
+			ranges = append(ranges, `rs.status between `+builder.arg(status.From)+` and `+builder.arg(status.To))
+		}
+		add(`exists (select 1 from Response rs where rs.request_id = Request.id and (` +
+			strings.Join(ranges, ` or `) + `))`)
+	}
+	if filter.From != nil {
+		add(`add >= ` + builder.arg(*filter.From))
+	}
+	if filter.To != nil {
+		add(`add < ` + builder.arg(*filter.To))
+	}
+	if filter.BodyContains != "" {
+		body := builder.arg([]byte(filter.BodyContains))
+		add(`(position(` + body + ` in body) > 0 or exists (select 1 from Response rs
+			where rs.request_id = Request.id and position(` + body + ` in rs.body) > 0))`)
+	}
+	if filter.HeaderContains != "" {
+		line := builder.arg("%" + escapeLike(filter.HeaderContains) + "%")
+		add(`(exists (select 1 from jsonb_array_elements(header) h
+			where (h->>'name') || ': ' || (h->>'value') ilike ` + line + `)
+			or exists (select 1 from Response rs, jsonb_array_elements(rs.header) h
+			where rs.request_id = Request.id and (h->>'name') || ': ' || (h->>'value') ilike ` + line + `))`)
+	}
+	if filter.ContentType != "" {
+		add(`exists (select 1 from Response rs, jsonb_array_elements(rs.header) h
+			where rs.request_id = Request.id and lower(h->>'name') = 'content-type'
+			and h->>'value' ilike ` + builder.arg(escapeLike(filter.ContentType)+"%") + `)`)
+	}
+	if len(filter.Tags) > 0 {
+		tags, _ := json.Marshal(filter.Tags)
+		add(`tags @> ` + builder.arg(string(tags)) + `::jsonb`)
+	}
+	if filter.Text != "" {
+		text := builder.arg("%" + escapeLike(filter.Text) + "%")
+		add(`(search ilike ` + text + ` or exists (select 1 from Response rs
+			where rs.request_id = Request.id and rs.search ilike ` + text + `))`)
+	}
+	for i := range filter.All {
+		add(builder.where(&filter.All[i]))
+	}
+	if len(filter.Any) > 0 {
+		var groups []string
+		for i := range filter.Any {
+			groups = append(groups, builder.where(&filter.Any[i]))
+		}
+		add(`(` + strings.Join(groups, ` or `) + `)`)
+	}
+
+	if len(conditions) == 0 {
+		return "true"
+	}
+	return `(` + strings.Join(conditions, ` and `) + `)`
+}
+
+// escapeLike escape special symbols of like pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
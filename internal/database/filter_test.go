@@ -0,0 +1,104 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		s, want string
+	}{
+		{"", ""},
+		{"/api/v1", "/api/v1"},
+		{"100%", `100\%`},
+		{"a_b", `a\_b`},
+		{`c:\dir`, `c:\\dir`},
+		{`\%_`, `\\\%\_`},
+	}
+	for _, test := range tests {
+		if got := escapeLike(test.s); got != test.want {
+			t.Errorf("escapeLike(%q) = %q, want %q", test.s, got, test.want)
+		}
+	}
+}
+
+func TestQueryBuilderWhere(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter models.HistoryFilter
+		where  string
+		args   []interface{}
+	}{
+		{
+			name:  "empty",
+			where: "true",
+		},
+		{
+			name:   "conditions",
+			filter: models.HistoryFilter{Scheme: "https", PathPrefix: "/a_b", Methods: []string{"get", "Post"}},
+			where:  "(scheme = $1 and path like $2 and method in ($3, $4))",
+			args:   []interface{}{"https", `/a\_b%`, "GET", "POST"},
+		},
+		{
+			name:   "exact host",
+			filter: models.HistoryFilter{Host: "Mail.RU", HostMatch: models.HostExact},
+			where:  "(" + requestHost + " = $1)",
+			args:   []interface{}{"mail.ru"},
+		},
+		{
+			name:   "suffix host",
+			filter: models.HistoryFilter{Host: "mail.ru", HostMatch: models.HostSuffix},
+			where:  "((" + requestHost + " = $1 or " + requestHost + " like $2))",
+			args:   []interface{}{"mail.ru", "%.mail.ru"},
+		},
+		{
+			name: "all group",
+			filter: models.HistoryFilter{
+				Scheme: "http",
+				All:    []models.HistoryFilter{{Methods: []string{"GET"}}, {PathPrefix: "/"}},
+			},
+			where: "(scheme = $1 and (method in ($2)) and (path like $3))",
+			args:  []interface{}{"http", "GET", "/%"},
+		},
+		{
+			name: "any group",
+			filter: models.HistoryFilter{
+				Any: []models.HistoryFilter{{Methods: []string{"PUT"}}, {Scheme: "https", PathPrefix: "/%"}},
+			},
+			where: "(((method in ($1)) or (scheme = $2 and path like $3)))",
+			args:  []interface{}{"PUT", "https", `/\%%`},
+		},
+		{
+			name: "empty filter in any group matches all",
+			filter: models.HistoryFilter{
+				Any: []models.HistoryFilter{{Scheme: "ws"}, {}},
+			},
+			where: "(((scheme = $1) or true))",
+			args:  []interface{}{"ws"},
+		},
+		{
+			name: "any inside all",
+			filter: models.HistoryFilter{
+				All: []models.HistoryFilter{{Any: []models.HistoryFilter{{Scheme: "http"}, {Scheme: "https"}}}},
+				Any: []models.HistoryFilter{{PathPrefix: "/a"}},
+			},
+			where: "((((scheme = $1) or (scheme = $2))) and ((path like $3)))",
+			args:  []interface{}{"http", "https", "/a%"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			builder := &queryBuilder{}
+			where := builder.where(&test.filter)
+			if where != test.where {
+				t.Errorf("where is\n%s\nwant\n%s", where, test.where)
+			}
+			if !reflect.DeepEqual(builder.args, test.args) {
+				t.Errorf("args are %#v, want %#v", builder.args, test.args)
+			}
+		})
+	}
+}
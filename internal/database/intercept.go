@@ -0,0 +1,100 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+// GetInterceptSettings return current settings of intercept mode
+// of project with projectID. Intercept mode is off until it is set
+func (db *DB) GetInterceptSettings(projectID int) (*models.InterceptSettings, error) {
+	statement := `select project_id, enabled, responses, host, method, path
+		from InterceptSettings where project_id = $1`
+	row := db.db.QueryRowx(statement, projectID)
+	settings := &models.InterceptSettings{}
+	err := row.StructScan(settings)
+	if err == sql.ErrNoRows {
+		return &models.InterceptSettings{ProjectID: projectID}, nil
+	}
+	return settings, err
+}
+
+// UpdateInterceptSettings replace settings of intercept mode
+// of project of settings
+func (db *DB) UpdateInterceptSettings(settings *models.InterceptSettings) error {
+	statement := `
+	INSERT INTO InterceptSettings(project_id, enabled, responses, host, method, path) VALUES
+		(:project_id, :enabled, :responses, :host, :method, :path)
+		ON CONFLICT (project_id) DO UPDATE SET enabled = :enabled,
+			responses = :responses, host = :host, method = :method, path = :path;
+		`
+	_, err := db.db.NamedExec(statement, settings)
+	return err
+}
+
+// CreateInterceptItem add held message to the queue of its project
+func (db *DB) CreateInterceptItem(item *models.InterceptItem) error {
+	if item.ProjectID == 0 {
+		item.ProjectID = models.DefaultProject
+	}
+	sqlInsert := `
+	INSERT INTO Intercept(kind, scheme, address, raw, project_id) VALUES
+		(:kind, :scheme, :address, :raw, :project_id)
+			RETURNING *;
+		`
+	return db.createAndReturnStruct(sqlInsert, item)
+}
+
+// GetInterceptItem return held message with id
+func (db *DB) GetInterceptItem(id int32) (*models.InterceptItem, error) {
+	statement := `select * from Intercept where id = $1`
+	row := db.db.QueryRowx(statement, id)
+	item := &models.InterceptItem{}
+	err := row.StructScan(item)
+	return item, err
+}
+
+// GetInterceptItems return messages of project with projectID
+// waiting for operator, older first
+func (db *DB) GetInterceptItems(projectID int) (*models.InterceptItems, error) {
+	statement := `select * from Intercept where state = $1 and project_id = $2 order by id`
+	rows, err := db.db.Queryx(statement, models.InterceptPending, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]models.InterceptItem, 0)
+	for rows.Next() {
+		var item models.InterceptItem
+		if err = rows.StructScan(&item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return &models.InterceptItems{Items: items}, rows.Err()
+}
+
+// DecideInterceptItem set state of pending message. Modified can be
+// empty to forward message as it is. sql.ErrNoRows is returned if
+// there is no such pending message
+func (db *DB) DecideInterceptItem(id int32, state, modified string) error {
+	statement := `update Intercept set state = $1, modified = $2 where id = $3 and state = $4`
+	result, err := db.db.Exec(statement, state, modified, id, models.InterceptPending)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err == nil && affected == 0 {
+		err = sql.ErrNoRows
+	}
+	return err
+}
+
+// DeleteInterceptItem remove message from the queue
+func (db *DB) DeleteInterceptItem(id int32) error {
+	statement := `delete from Intercept where id = $1`
+	_, err := db.db.Exec(statement, id)
+	return err
+}
@@ -0,0 +1,552 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+// memoryEntry - request with its responses and websocket messages
+type memoryEntry struct {
+	request   models.RequestDB
+	responses []models.ResponseDB
+	messages  []models.WebSocketMessage
+}
+
+// MemoryDB - store in memory, that keeps the last capacity requests
+// of history. The oldest requests are evicted with their responses
+// and messages. Nothing is kept after restart
+type MemoryDB struct {
+	mutex sync.RWMutex
+
+	// ring - requests in order of ids from start, count of them is used
+	ring         []*memoryEntry
+	start, count int
+	byID         map[int]*memoryEntry
+
+	lastRequest, lastResponse, lastMessage int
+
+	settings      map[int]models.InterceptSettings
+	intercepted   []models.InterceptItem
+	lastIntercept int
+	rules         []models.RuleDB
+	lastRule      int
+	projects      []models.ProjectDB
+	lastProject   int
+}
+
+// NewMemory make store in memory for capacity requests
+func NewMemory(capacity int) (*MemoryDB, error) {
+	if capacity <= 0 {
+		return nil, errors.New("capacity of memory store must be positive")
+	}
+	return &MemoryDB{
+		ring:     make([]*memoryEntry, capacity),
+		byID:     make(map[int]*memoryEntry, capacity),
+		settings: make(map[int]models.InterceptSettings),
+		projects: []models.ProjectDB{{
+			ID:     models.DefaultProject,
+			Name:   "default",
+			Active: true,
+			Add:    time.Now(),
+		}},
+		lastProject: models.DefaultProject,
+	}, nil
+}
+
+func (db *MemoryDB) Close() error {
+	return nil
+}
+
+// at return entry number i from the oldest one
+func (db *MemoryDB) at(i int) *memoryEntry {
+	return db.ring[(db.start+i)%len(db.ring)]
+}
+
+// CreateRequest add request to store, evicting the oldest
+// request of any project, if the store is full
+func (db *MemoryDB) CreateRequest(rdb *models.RequestDB) error {
+	rdb.Search = rdb.SearchText()
+	if rdb.Add.IsZero() {
+		rdb.Add = time.Now()
+	}
+	if rdb.Tags == nil {
+		rdb.Tags = models.Tags{}
+	}
+	if rdb.Body == nil {
+		rdb.Body = []byte{}
+	}
+	if rdb.ProjectID == 0 {
+		rdb.ProjectID = models.DefaultProject
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	if db.projectIndex(rdb.ProjectID) < 0 {
+		return errors.New("no project with such id")
+	}
+	db.lastRequest++
+	rdb.ID = db.lastRequest
+	entry := &memoryEntry{request: *rdb}
+	// response is stored separately
+	entry.request.Response = nil
+
+	if db.count == len(db.ring) {
+		delete(db.byID, db.ring[db.start].request.ID)
+		db.ring[db.start] = entry
+		db.start = (db.start + 1) % len(db.ring)
+	} else {
+		db.ring[(db.start+db.count)%len(db.ring)] = entry
+		db.count++
+	}
+	db.byID[rdb.ID] = entry
+	return nil
+}
+
+// GetRequests return page of requests of history matching query
+// with cursor of the next page and total number of matching requests
+func (db *MemoryDB) GetRequests(query models.HistoryQuery) (*models.RequestsDB, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+	page := &historyPage{query: query}
+	for i := 0; i < db.count; i++ {
+		entry := db.at(i)
+		if query.Desc {
+			entry = db.at(db.count - 1 - i)
+		}
+		page.add(&entry.request, entry.responses)
+	}
+	return page.result(), nil
+}
+
+func (db *MemoryDB) GetRequest(id int32) (*models.RequestDB, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+	entry, ok := db.byID[int(id)]
+	if !ok {
+		return &models.RequestDB{}, sql.ErrNoRows
+	}
+	request := entry.request
+	return &request, nil
+}
+
+// SetRequestTags replace tags of request with id
+func (db *MemoryDB) SetRequestTags(id int32, tags models.Tags) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	entry, ok := db.byID[int(id)]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	entry.request.Tags = tags
+	return nil
+}
+
+// DeleteRequest remove request with id with its responses and messages
+func (db *MemoryDB) DeleteRequest(id int32) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	db.deleteRequests(func(request *models.RequestDB) bool {
+		return request.ID == int(id)
+	})
+	return nil
+}
+
+// DeleteRequests remove requests of project with projectID
+// with their responses and messages. Ids are not reused
+func (db *MemoryDB) DeleteRequests(projectID int) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	db.deleteRequests(func(request *models.RequestDB) bool {
+		return request.ProjectID == projectID
+	})
+	return nil
+}
+
+// deleteRequests rebuild ring without requests, for which remove
+// returns true
+func (db *MemoryDB) deleteRequests(remove func(request *models.RequestDB) bool) {
+	ring := make([]*memoryEntry, len(db.ring))
+	count := 0
+	for i := 0; i < db.count; i++ {
+		entry := db.at(i)
+		if remove(&entry.request) {
+			delete(db.byID, entry.request.ID)
+			continue
+		}
+		ring[count] = entry
+		count++
+	}
+	db.ring, db.start, db.count = ring, 0, count
+}
+
+// CreateResponse add response to store
+func (db *MemoryDB) CreateResponse(resp *models.ResponseDB) error {
+	resp.Search = resp.SearchText()
+	if resp.Add.IsZero() {
+		resp.Add = time.Now()
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	entry, ok := db.byID[resp.RequestID]
+	if !ok {
+		return errors.New("no request with such id")
+	}
+	db.lastResponse++
+	resp.ID = db.lastResponse
+	entry.responses = append(entry.responses, *resp)
+	return nil
+}
+
+// GetResponse return response to the request with id requestID
+func (db *MemoryDB) GetResponse(requestID int32) (*models.ResponseDB, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+	entry, ok := db.byID[int(requestID)]
+	if !ok || len(entry.responses) == 0 {
+		return &models.ResponseDB{}, sql.ErrNoRows
+	}
+	response := entry.responses[len(entry.responses)-1]
+	return &response, nil
+}
+
+// GetResponses return the latest responses to requests with ids
+// by the id of request
+func (db *MemoryDB) GetResponses(requestIDs []int) (map[int]*models.ResponseDB, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+	responses := make(map[int]*models.ResponseDB, len(requestIDs))
+	for _, id := range requestIDs {
+		if entry, ok := db.byID[id]; ok && len(entry.responses) > 0 {
+			response := entry.responses[len(entry.responses)-1]
+			responses[id] = &response
+		}
+	}
+	return responses, nil
+}
+
+// CreateWebSocketMessage add websocket message to store
+func (db *MemoryDB) CreateWebSocketMessage(message *models.WebSocketMessage) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	entry, ok := db.byID[message.RequestID]
+	if !ok {
+		return errors.New("no request with such id")
+	}
+	db.lastMessage++
+	message.ID = db.lastMessage
+	entry.messages = append(entry.messages, *message)
+	return nil
+}
+
+// GetWebSocketMessages return messages of websocket connection,
+// opened by the request with id requestID
+func (db *MemoryDB) GetWebSocketMessages(requestID int32) (*models.WebSocketMessages, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+	messages := make([]models.WebSocketMessage, 0)
+	if entry, ok := db.byID[int(requestID)]; ok {
+		messages = append(messages, entry.messages...)
+	}
+	return &models.WebSocketMessages{Messages: messages}, nil
+}
+
+// GetInterceptSettings return current settings of intercept mode
+// of project with projectID
+func (db *MemoryDB) GetInterceptSettings(projectID int) (*models.InterceptSettings, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+	settings := db.settings[projectID]
+	settings.ProjectID = projectID
+	return &settings, nil
+}
+
+// UpdateInterceptSettings replace settings of intercept mode
+// of project of settings
+func (db *MemoryDB) UpdateInterceptSettings(settings *models.InterceptSettings) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	db.settings[settings.ProjectID] = *settings
+	return nil
+}
+
+// CreateInterceptItem add held message to the queue of its project
+func (db *MemoryDB) CreateInterceptItem(item *models.InterceptItem) error {
+	if item.ProjectID == 0 {
+		item.ProjectID = models.DefaultProject
+	}
+	if item.State == "" {
+		item.State = models.InterceptPending
+	}
+	if item.Add.IsZero() {
+		item.Add = time.Now()
+	}
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	db.lastIntercept++
+	item.ID = db.lastIntercept
+	db.intercepted = append(db.intercepted, *item)
+	return nil
+}
+
+// interceptIndex return index of held message with id or -1
+func (db *MemoryDB) interceptIndex(id int32) int {
+	for i := range db.intercepted {
+		if db.intercepted[i].ID == int(id) {
+			return i
+		}
+	}
+	return -1
+}
+
+// GetInterceptItem return held message with id
+func (db *MemoryDB) GetInterceptItem(id int32) (*models.InterceptItem, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+	i := db.interceptIndex(id)
+	if i < 0 {
+		return &models.InterceptItem{}, sql.ErrNoRows
+	}
+	item := db.intercepted[i]
+	return &item, nil
+}
+
+// GetInterceptItems return messages of project with projectID
+// waiting for operator, older first
+func (db *MemoryDB) GetInterceptItems(projectID int) (*models.InterceptItems, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+	items := make([]models.InterceptItem, 0)
+	for _, item := range db.intercepted {
+		if item.State == models.InterceptPending && item.ProjectID == projectID {
+			items = append(items, item)
+		}
+	}
+	return &models.InterceptItems{Items: items}, nil
+}
+
+// DecideInterceptItem set state of pending message. Modified can be
+// empty to forward message as it is. sql.ErrNoRows is returned if
+// there is no such pending message
+func (db *MemoryDB) DecideInterceptItem(id int32, state, modified string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	i := db.interceptIndex(id)
+	if i < 0 || db.intercepted[i].State != models.InterceptPending {
+		return sql.ErrNoRows
+	}
+	db.intercepted[i].State, db.intercepted[i].Modified = state, modified
+	return nil
+}
+
+// DeleteInterceptItem remove message from the queue
+func (db *MemoryDB) DeleteInterceptItem(id int32) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	if i := db.interceptIndex(id); i >= 0 {
+		db.intercepted = append(db.intercepted[:i], db.intercepted[i+1:]...)
+	}
+	return nil
+}
+
+// CreateRule add match and replace rule of its project to store
+func (db *MemoryDB) CreateRule(rule *models.RuleDB) error {
+	if rule.ProjectID == 0 {
+		rule.ProjectID = models.DefaultProject
+	}
+	if rule.Add.IsZero() {
+		rule.Add = time.Now()
+	}
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	db.lastRule++
+	rule.ID = db.lastRule
+	db.rules = append(db.rules, *rule)
+	return nil
+}
+
+// ruleIndex return index of rule with id or -1
+func (db *MemoryDB) ruleIndex(id int) int {
+	for i := range db.rules {
+		if db.rules[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// UpdateRule replace rule with the same id, project of rule is not
+// changed. sql.ErrNoRows is returned if there is no such rule
+func (db *MemoryDB) UpdateRule(rule *models.RuleDB) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	i := db.ruleIndex(rule.ID)
+	if i < 0 {
+		return sql.ErrNoRows
+	}
+	rule.Add, rule.ProjectID = db.rules[i].Add, db.rules[i].ProjectID
+	db.rules[i] = *rule
+	return nil
+}
+
+// GetRules return rules of project with projectID in order of creation
+func (db *MemoryDB) GetRules(projectID int) (*models.RulesDB, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+	rules := make([]models.RuleDB, 0)
+	for _, rule := range db.rules {
+		if rule.ProjectID == projectID {
+			rules = append(rules, rule)
+		}
+	}
+	return &models.RulesDB{Rules: rules}, nil
+}
+
+func (db *MemoryDB) GetRule(id int32) (*models.RuleDB, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+	i := db.ruleIndex(int(id))
+	if i < 0 {
+		return &models.RuleDB{}, sql.ErrNoRows
+	}
+	rule := db.rules[i]
+	return &rule, nil
+}
+
+// DeleteRule remove rule with id. sql.ErrNoRows is returned if
+// there is no such rule
+func (db *MemoryDB) DeleteRule(id int32) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	i := db.ruleIndex(int(id))
+	if i < 0 {
+		return sql.ErrNoRows
+	}
+	db.rules = append(db.rules[:i], db.rules[i+1:]...)
+	return nil
+}
+
+// projectIndex return index of project with id or -1
+func (db *MemoryDB) projectIndex(id int) int {
+	for i := range db.projects {
+		if db.projects[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// CreateProject add project to store. New project is not active
+func (db *MemoryDB) CreateProject(project *models.ProjectDB) error {
+	project.Active = false
+	if project.Add.IsZero() {
+		project.Add = time.Now()
+	}
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	db.lastProject++
+	project.ID = db.lastProject
+	db.projects = append(db.projects, *project)
+	return nil
+}
+
+// GetProjects return all projects in order of creation
+func (db *MemoryDB) GetProjects() (*models.ProjectsDB, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+	projects := make([]models.ProjectDB, len(db.projects))
+	copy(projects, db.projects)
+	return &models.ProjectsDB{Projects: projects}, nil
+}
+
+func (db *MemoryDB) GetProject(id int32) (*models.ProjectDB, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+	i := db.projectIndex(int(id))
+	if i < 0 {
+		return &models.ProjectDB{}, sql.ErrNoRows
+	}
+	project := db.projects[i]
+	return &project, nil
+}
+
+// GetActiveProject return project, the proxy records to
+func (db *MemoryDB) GetActiveProject() (*models.ProjectDB, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+	for _, project := range db.projects {
+		if project.Active {
+			return &project, nil
+		}
+	}
+	return &models.ProjectDB{}, sql.ErrNoRows
+}
+
+// UpdateProject change name and archived flag of project with
+// the same id. sql.ErrNoRows is returned if there is no such project
+func (db *MemoryDB) UpdateProject(project *models.ProjectDB) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	i := db.projectIndex(project.ID)
+	if i < 0 {
+		return sql.ErrNoRows
+	}
+	db.projects[i].Name, db.projects[i].Archived = project.Name, project.Archived
+	*project = db.projects[i]
+	return nil
+}
+
+// ActivateProject make project with id the only active one.
+// sql.ErrNoRows is returned if there is no such project
+// or it is archived
+func (db *MemoryDB) ActivateProject(id int32) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	i := db.projectIndex(int(id))
+	if i < 0 || db.projects[i].Archived {
+		return sql.ErrNoRows
+	}
+	for j := range db.projects {
+		db.projects[j].Active = j == i
+	}
+	return nil
+}
+
+// DeleteProject remove project with id with all its traffic, rules
+// and intercepted messages. sql.ErrNoRows is returned if there is no
+// such project, ErrDefaultProject for default project
+func (db *MemoryDB) DeleteProject(id int32) error {
+	if id == models.DefaultProject {
+		return ErrDefaultProject
+	}
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	i := db.projectIndex(int(id))
+	if i < 0 {
+		return sql.ErrNoRows
+	}
+	db.deleteRequests(func(request *models.RequestDB) bool {
+		return request.ProjectID == int(id)
+	})
+	rules := db.rules[:0]
+	for _, rule := range db.rules {
+		if rule.ProjectID != int(id) {
+			rules = append(rules, rule)
+		}
+	}
+	db.rules = rules
+	intercepted := db.intercepted[:0]
+	for _, item := range db.intercepted {
+		if item.ProjectID != int(id) {
+			intercepted = append(intercepted, item)
+		}
+	}
+	db.intercepted = intercepted
+	delete(db.settings, int(id))
+	db.projects = append(db.projects[:i], db.projects[i+1:]...)
+	return nil
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+func TestNewMemory(t *testing.T) {
+	for _, capacity := range []int{0, -1} {
+		if _, err := NewMemory(capacity); err == nil {
+			t.Errorf("memory store of capacity %d is created", capacity)
+		}
+	}
+}
+
+func TestMemoryEviction(t *testing.T) {
+	db, err := NewMemory(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		request := &models.RequestDB{Method: "GET", ProjectID: models.DefaultProject}
+		if err = db.CreateRequest(request); err != nil {
+			t.Fatal(err)
+		}
+		if err = db.CreateResponse(&models.ResponseDB{RequestID: request.ID, Status: 200}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	requestsDB, err := db.GetRequests(models.HistoryQuery{Limit: 10, Project: models.DefaultProject})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []int
+	for _, request := range requestsDB.Requests {
+		got = append(got, request.ID)
+	}
+	if !reflect.DeepEqual(got, []int{3, 4, 5}) || requestsDB.Total != 3 {
+		t.Errorf("memory store keeps requests %v of %d, want the newest ones", got, requestsDB.Total)
+	}
+	if _, err = db.GetRequest(1); err != sql.ErrNoRows {
+		t.Errorf("evicted request is got with error %v", err)
+	}
+	if _, err = db.GetResponse(1); err != sql.ErrNoRows {
+		t.Errorf("response of evicted request is got with error %v", err)
+	}
+	responses, err := db.GetResponses([]int{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(responses) != 1 || responses[3] == nil {
+		t.Errorf("got responses %v, want only the response of kept request 3", responses)
+	}
+}
@@ -0,0 +1,306 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// baselineVersion - version of schema made by the old init script
+const baselineVersion = 1
+
+// migrationLock - key of advisory lock, that keeps services
+// started together from migrating at the same time
+const migrationLock = 7281530
+
+// Migration - change of schema. Migrations are applied in order of
+// versions, applied versions are kept in schema_migrations table
+type Migration struct {
+	Version   int
+	Name      string
+	statement string
+}
+
+// migrations - all changes of schema. Add new ones to the end and
+// never change applied ones
+var migrations = []Migration{
+	{
+		Version: baselineVersion,
+		Name:    "baseline schema",
+		// the schema of the old init script. Databases made by it
+		// have this migration marked applied, see stampBaseline
+		statement: `
+CREATE TABLE Request (
+  id SERIAL PRIMARY KEY,
+  method text NOT NULL,
+  scheme text NOT NULL,
+	address text NOT NULL,
+	header text default '',
+	body text default '',
+	userLogin text default '',
+	userPassword text default '',
+	add TIMESTAMPTZ default now()
+);
+`,
+	},
+	{
+		Version: 2,
+		Name:    "traffic schema",
+		// header was kept as "Name : value" lines separated by CRLF,
+		// body as text
+		statement: `
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE FUNCTION pg_temp.header_lines_to_json(lines text) RETURNS jsonb AS $$
+	SELECT coalesce(jsonb_agg(jsonb_build_object(
+		'name', split_part(line, ' : ', 1),
+		'value', substr(line, strpos(line, ' : ') + 3)) ORDER BY n), '[]')
+	FROM regexp_split_to_table(coalesce(lines, ''), E'\r\n') WITH ORDINALITY AS header(line, n)
+	WHERE strpos(line, ' : ') > 0
+$$ LANGUAGE sql IMMUTABLE;
+
+ALTER TABLE Request
+	ALTER COLUMN header DROP DEFAULT,
+	ALTER COLUMN header TYPE jsonb USING pg_temp.header_lines_to_json(header),
+	ALTER COLUMN header SET DEFAULT '[]',
+	ALTER COLUMN body DROP DEFAULT,
+	ALTER COLUMN body TYPE bytea USING convert_to(coalesce(body, ''), 'UTF8'),
+	ALTER COLUMN body SET DEFAULT '',
+	ADD COLUMN path text default '',
+	ADD COLUMN query text default '',
+	ADD COLUMN url text default '',
+	ADD COLUMN proto text default '',
+	ADD COLUMN truncated boolean default false,
+	ADD COLUMN tags jsonb default '[]',
+	ADD COLUMN parent integer REFERENCES Request(id) ON DELETE SET NULL,
+	ADD COLUMN search text default '';
+
+DROP FUNCTION pg_temp.header_lines_to_json(text);
+
+CREATE TABLE Response (
+  id SERIAL PRIMARY KEY,
+  request_id integer NOT NULL REFERENCES Request(id) ON DELETE CASCADE,
+  proto text default '',
+  status integer NOT NULL,
+	header jsonb default '[]',
+	body bytea default '',
+	size bigint default 0,
+	truncated boolean default false,
+	search text default '',
+	duration bigint default 0,
+	add TIMESTAMPTZ default now()
+);
+
+CREATE INDEX response_request_id_idx ON Response(request_id);
+
+-- search over url, headers and text bodies
+CREATE INDEX request_search_idx ON Request USING gin (search gin_trgm_ops);
+CREATE INDEX response_search_idx ON Response USING gin (search gin_trgm_ops);
+
+CREATE TABLE WebSocketMessage (
+  id SERIAL PRIMARY KEY,
+  request_id integer NOT NULL REFERENCES Request(id) ON DELETE CASCADE,
+  direction text NOT NULL,
+  opcode integer NOT NULL,
+	payload bytea,
+	add TIMESTAMPTZ default now()
+);
+
+CREATE INDEX websocketmessage_request_id_idx ON WebSocketMessage(request_id);
+
+CREATE TABLE InterceptSettings (
+  id integer PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+  enabled boolean default false,
+  responses boolean default false,
+	host text default '',
+	method text default '',
+	path text default ''
+);
+
+INSERT INTO InterceptSettings(id) VALUES (1);
+
+CREATE TABLE Intercept (
+  id SERIAL PRIMARY KEY,
+  kind text NOT NULL,
+  scheme text NOT NULL,
+	address text NOT NULL,
+	raw text default '',
+	modified text default '',
+	state text NOT NULL default 'pending',
+	add TIMESTAMPTZ default now()
+);
+
+CREATE TABLE Rule (
+  id SERIAL PRIMARY KEY,
+  enabled boolean default true,
+  target text NOT NULL,
+	host text default '',
+	method text default '',
+	path text default '',
+	pattern text default '',
+	replacement text default '',
+	regex boolean default false,
+	comment text default '',
+	add TIMESTAMPTZ default now()
+);
+`,
+	},
+	{
+		Version: 3,
+		Name:    "projects",
+		// existing traffic goes to the default project
+		statement: `
+CREATE TABLE Project (
+  id SERIAL PRIMARY KEY,
+  name text NOT NULL,
+	archived boolean default false,
+	active boolean default false,
+	add TIMESTAMPTZ default now()
+);
+
+INSERT INTO Project(id, name, active) VALUES (1, 'default', true);
+SELECT setval('project_id_seq', (SELECT max(id) FROM Project));
+
+ALTER TABLE Request ADD COLUMN project_id integer NOT NULL default 1
+	REFERENCES Project(id) ON DELETE CASCADE;
+CREATE INDEX request_project_id_idx ON Request(project_id);
+`,
+	},
+	{
+		Version: 4,
+		Name:    "rules and intercept of projects",
+		// existing rules, settings and held messages go to the
+		// default project
+		statement: `
+ALTER TABLE Rule ADD COLUMN project_id integer NOT NULL default 1
+	REFERENCES Project(id) ON DELETE CASCADE;
+CREATE INDEX rule_project_id_idx ON Rule(project_id);
+
+ALTER TABLE Intercept ADD COLUMN project_id integer NOT NULL default 1
+	REFERENCES Project(id) ON DELETE CASCADE;
+CREATE INDEX intercept_project_id_idx ON Intercept(project_id);
+
+-- settings were one row with id 1, now there is a row per project
+ALTER TABLE InterceptSettings ADD COLUMN project_id integer NOT NULL default 1
+	REFERENCES Project(id) ON DELETE CASCADE;
+ALTER TABLE InterceptSettings DROP COLUMN id;
+ALTER TABLE InterceptSettings ADD PRIMARY KEY (project_id);
+ALTER TABLE InterceptSettings ALTER COLUMN project_id DROP DEFAULT;
+`,
+	},
+}
+
+// Migrate apply migrations, that were not applied yet, and return
+// them. With dryRun nothing is changed, pending migrations are
+// only returned
+func (db *DB) Migrate(dryRun bool) ([]Migration, error) {
+	tx, err := db.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if !dryRun {
+		// lock is held until the end of transaction
+		if _, err = tx.Exec(`select pg_advisory_xact_lock($1)`, migrationLock); err != nil {
+			return nil, err
+		}
+		if err = stampBaseline(tx); err != nil {
+			return nil, err
+		}
+		if _, err = tx.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+			version integer PRIMARY KEY,
+			name text NOT NULL,
+			applied TIMESTAMPTZ default now()
+		)`); err != nil {
+			return nil, err
+		}
+	}
+
+	applied, err := appliedVersions(tx)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, migration := range migrations {
+		if !applied[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+	if dryRun {
+		return pending, nil
+	}
+
+	for _, migration := range pending {
+		if _, err = tx.Exec(migration.statement); err != nil {
+			return nil, fmt.Errorf("migration %d (%s): %v", migration.Version, migration.Name, err)
+		}
+		if _, err = tx.Exec(`insert into schema_migrations(version, name) values ($1, $2)`,
+			migration.Version, migration.Name); err != nil {
+			return nil, err
+		}
+	}
+	return pending, tx.Commit()
+}
+
+// stampBaseline mark baseline migration applied in database made
+// by the old init script, that has Request table without
+// schema_migrations, so the next migrations upgrade its tables
+func stampBaseline(tx *sqlx.Tx) error {
+	legacy, err := isLegacy(tx)
+	if err != nil || !legacy {
+		return err
+	}
+	if _, err = tx.Exec(`CREATE TABLE schema_migrations (
+			version integer PRIMARY KEY,
+			name text NOT NULL,
+			applied TIMESTAMPTZ default now()
+		)`); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`insert into schema_migrations(version, name) values ($1, $2)`,
+		baselineVersion, migrations[0].Name)
+	return err
+}
+
+// isLegacy report if database was made by the old init script
+func isLegacy(tx *sqlx.Tx) (bool, error) {
+	migrated, err := tableExists(tx, "schema_migrations")
+	if err != nil || migrated {
+		return false, err
+	}
+	return tableExists(tx, "request")
+}
+
+func tableExists(tx *sqlx.Tx, name string) (bool, error) {
+	var exists sql.NullString
+	err := tx.Get(&exists, `select to_regclass($1)::text`, name)
+	return exists.Valid, err
+}
+
+// appliedVersions return versions from schema_migrations, none
+// if there is no such table yet, or the baseline one for database
+// made by the old init script
+func appliedVersions(tx *sqlx.Tx) (map[int]bool, error) {
+	applied := make(map[int]bool)
+	exists, err := tableExists(tx, "schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		legacy, err := tableExists(tx, "request")
+		if legacy {
+			applied[baselineVersion] = true
+		}
+		return applied, err
+	}
+	var versions []int
+	if err := tx.Select(&versions, `select version from schema_migrations`); err != nil {
+		return nil, err
+	}
+	for _, version := range versions {
+		applied[version] = true
+	}
+	return applied, nil
+}
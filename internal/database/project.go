@@ -0,0 +1,112 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+// CreateProject add project to database. New project is not active
+func (db *DB) CreateProject(project *models.ProjectDB) error {
+	sqlInsert := `
+	INSERT INTO Project(name, archived) VALUES
+		(:name, :archived)
+			RETURNING *;
+		`
+	return db.createAndReturnStruct(sqlInsert, project)
+}
+
+// GetProjects return all projects in order of creation
+func (db *DB) GetProjects() (*models.ProjectsDB, error) {
+	statement := `select * from Project order by id`
+	rows, err := db.db.Queryx(statement)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	projects := make([]models.ProjectDB, 0)
+	for rows.Next() {
+		var project models.ProjectDB
+		if err = rows.StructScan(&project); err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+	return &models.ProjectsDB{Projects: projects}, rows.Err()
+}
+
+func (db *DB) GetProject(id int32) (*models.ProjectDB, error) {
+	statement := `select * from Project where id = $1`
+	row := db.db.QueryRowx(statement, id)
+	project := &models.ProjectDB{}
+	err := row.StructScan(project)
+	return project, err
+}
+
+// GetActiveProject return project, the proxy records to
+func (db *DB) GetActiveProject() (*models.ProjectDB, error) {
+	statement := `select * from Project where active limit 1`
+	row := db.db.QueryRowx(statement)
+	project := &models.ProjectDB{}
+	err := row.StructScan(project)
+	return project, err
+}
+
+// UpdateProject change name and archived flag of project with
+// the same id. sql.ErrNoRows is returned if there is no such project
+func (db *DB) UpdateProject(project *models.ProjectDB) error {
+	sqlUpdate := `
+	UPDATE Project SET name = :name, archived = :archived
+		WHERE id = :id
+			RETURNING *;
+		`
+	rows, err := db.db.NamedQuery(sqlUpdate, project)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err = rows.Err(); err == nil {
+			err = sql.ErrNoRows
+		}
+		return err
+	}
+	return rows.StructScan(project)
+}
+
+// ActivateProject make project with id the only active one.
+// sql.ErrNoRows is returned if there is no such project
+// or it is archived
+func (db *DB) ActivateProject(id int32) error {
+	statement := `update Project set active = (id = $1)
+		where exists (select 1 from Project where id = $1 and not archived)`
+	result, err := db.db.Exec(statement, id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err == nil && affected == 0 {
+		err = sql.ErrNoRows
+	}
+	return err
+}
+
+// DeleteProject remove project with id with all its traffic, rules
+// and intercepted messages. sql.ErrNoRows is returned if there is no
+// such project, ErrDefaultProject for default project
+func (db *DB) DeleteProject(id int32) error {
+	if id == models.DefaultProject {
+		return ErrDefaultProject
+	}
+	statement := `delete from Project where id = $1`
+	result, err := db.db.Exec(statement, id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err == nil && affected == 0 {
+		err = sql.ErrNoRows
+	}
+	return err
+}
@@ -0,0 +1,162 @@
+package database
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+func TestGetRequestsProject(t *testing.T) {
+	tests := []struct {
+		name    string
+		project int
+		filter  models.HistoryFilter
+		want    []int
+	}{
+		{"default project", models.DefaultProject, models.HistoryFilter{}, []int{1, 2, 3, 4}},
+		{"other project", 2, models.HistoryFilter{}, []int{5}},
+		{"other project filtered", 2, models.HistoryFilter{Host: "mail.ru", HostMatch: models.HostExact}, []int{5}},
+		{"all projects", 0, models.HistoryFilter{Host: "mail.ru", HostMatch: models.HostExact}, []int{1, 5}},
+		{"unknown project", 3, models.HistoryFilter{}, []int{}},
+	}
+	for name, db := range stores(t) {
+		fillHistory(t, db)
+		for _, test := range tests {
+			t.Run(name+"/"+test.name, func(t *testing.T) {
+				requestsDB, err := db.GetRequests(models.HistoryQuery{
+					Filter:  test.filter,
+					Limit:   10,
+					Project: test.project,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := ids(requestsDB); !reflect.DeepEqual(got, test.want) {
+					t.Errorf("got requests %v, want %v", got, test.want)
+				}
+			})
+		}
+	}
+}
+
+func TestDeleteProject(t *testing.T) {
+	for name, db := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			fillHistory(t, db)
+			const other = 2
+			if err := db.CreateRule(&models.RuleDB{ProjectID: other, Target: models.RuleRequestHeader}); err != nil {
+				t.Fatal(err)
+			}
+			if err := db.UpdateInterceptSettings(&models.InterceptSettings{ProjectID: other, Enabled: true}); err != nil {
+				t.Fatal(err)
+			}
+			if err := db.CreateInterceptItem(&models.InterceptItem{ProjectID: other}); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := db.DeleteProject(models.DefaultProject); err != ErrDefaultProject {
+				t.Errorf("default project is deleted with error %v", err)
+			}
+			if err := db.DeleteProject(other); err != nil {
+				t.Fatal(err)
+			}
+			if err := db.DeleteProject(other); err != sql.ErrNoRows {
+				t.Errorf("removed project is deleted again with error %v", err)
+			}
+			if _, err := db.GetRequest(5); err != sql.ErrNoRows {
+				t.Errorf("request of removed project is got with error %v", err)
+			}
+			requestsDB, err := db.GetRequests(models.HistoryQuery{Limit: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ids(requestsDB); !reflect.DeepEqual(got, []int{1, 2, 3, 4}) {
+				t.Errorf("requests %v are left, want requests of default project", got)
+			}
+			rules, err := db.GetRules(other)
+			if err != nil || len(rules.Rules) > 0 {
+				t.Errorf("rules %v of removed project are left, error %v", rules, err)
+			}
+			settings, err := db.GetInterceptSettings(other)
+			if err != nil || settings.Enabled {
+				t.Errorf("intercept settings %v of removed project are left, error %v", settings, err)
+			}
+			items, err := db.GetInterceptItems(other)
+			if err != nil || len(items.Items) > 0 {
+				t.Errorf("held messages %v of removed project are left, error %v", items, err)
+			}
+		})
+	}
+}
+
+func TestProjectScope(t *testing.T) {
+	for name, db := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			other := &models.ProjectDB{Name: "other"}
+			if err := db.CreateProject(other); err != nil {
+				t.Fatal(err)
+			}
+			// project is not set for default one
+			if err := db.CreateRule(&models.RuleDB{Target: models.RuleRequestHeader}); err != nil {
+				t.Fatal(err)
+			}
+			rule := &models.RuleDB{ProjectID: other.ID, Target: models.RuleResponseBody}
+			if err := db.CreateRule(rule); err != nil {
+				t.Fatal(err)
+			}
+			// project of rule is not changed by update
+			rule.ProjectID, rule.Comment = models.DefaultProject, "updated"
+			if err := db.UpdateRule(rule); err != nil {
+				t.Fatal(err)
+			}
+			if err := db.UpdateInterceptSettings(&models.InterceptSettings{ProjectID: other.ID, Enabled: true, Host: "mail.ru"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := db.CreateInterceptItem(&models.InterceptItem{Kind: models.InterceptRequest}); err != nil {
+				t.Fatal(err)
+			}
+
+			tests := []struct {
+				project   int
+				rules     []string
+				intercept bool
+				items     int
+			}{
+				{models.DefaultProject, []string{models.RuleRequestHeader}, false, 1},
+				{other.ID, []string{models.RuleResponseBody}, true, 0},
+			}
+			for _, test := range tests {
+				rulesDB, err := db.GetRules(test.project)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var targets []string
+				for _, rule := range rulesDB.Rules {
+					if rule.ProjectID != test.project {
+						t.Errorf("rule %d of project %d is got for project %d", rule.ID, rule.ProjectID, test.project)
+					}
+					targets = append(targets, rule.Target)
+				}
+				if !reflect.DeepEqual(targets, test.rules) {
+					t.Errorf("project %d has rules %v, want %v", test.project, targets, test.rules)
+				}
+				settings, err := db.GetInterceptSettings(test.project)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if settings.ProjectID != test.project || settings.Enabled != test.intercept {
+					t.Errorf("project %d has intercept settings %+v", test.project, settings)
+				}
+				items, err := db.GetInterceptItems(test.project)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(items.Items) != test.items {
+					t.Errorf("project %d has %d held messages, want %d", test.project, len(items.Items), test.items)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+// CreateRule add match and replace rule of its project to database
+func (db *DB) CreateRule(rule *models.RuleDB) error {
+	if rule.ProjectID == 0 {
+		rule.ProjectID = models.DefaultProject
+	}
+	sqlInsert := `
+	INSERT INTO Rule(enabled, target, host, method, path, pattern,
+		replacement, regex, comment, project_id) VALUES
+		(:enabled, :target, :host, :method, :path, :pattern,
+			:replacement, :regex, :comment, :project_id)
+			RETURNING *;
+		`
+	return db.createAndReturnStruct(sqlInsert, rule)
+}
+
+// UpdateRule replace rule with the same id, project of rule is not
+// changed. sql.ErrNoRows is returned if there is no such rule
+func (db *DB) UpdateRule(rule *models.RuleDB) error {
+	sqlUpdate := `
+	UPDATE Rule SET enabled = :enabled, target = :target, host = :host,
+		method = :method, path = :path, pattern = :pattern,
+		replacement = :replacement, regex = :regex, comment = :comment
+		WHERE id = :id
+			RETURNING *;
+		`
+	rows, err := db.db.NamedQuery(sqlUpdate, rule)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err = rows.Err(); err == nil {
+			err = sql.ErrNoRows
+		}
+		return err
+	}
+	return rows.StructScan(rule)
+}
+
+// GetRules return rules of project with projectID in order of creation
+func (db *DB) GetRules(projectID int) (*models.RulesDB, error) {
+	statement := `select * from Rule where project_id = $1 order by id`
+	rows, err := db.db.Queryx(statement, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := make([]models.RuleDB, 0)
+	for rows.Next() {
+		var rule models.RuleDB
+		if err = rows.StructScan(&rule); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return &models.RulesDB{Rules: rules}, rows.Err()
+}
+
+func (db *DB) GetRule(id int32) (*models.RuleDB, error) {
+	statement := `select * from Rule where id = $1`
+	row := db.db.QueryRowx(statement, id)
+	rule := &models.RuleDB{}
+	err := row.StructScan(rule)
+	return rule, err
+}
+
+// DeleteRule remove rule with id. sql.ErrNoRows is returned if
+// there is no such rule
+func (db *DB) DeleteRule(id int32) error {
+	statement := `delete from Rule where id = $1`
+	result, err := db.db.Exec(statement, id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err == nil && affected == 0 {
+		err = sql.ErrNoRows
+	}
+	return err
+}
@@ -0,0 +1,191 @@
+package database
+
+import (
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+// Store - storage of traffic, intercepted messages and rules.
+// Methods looking for one record return sql.ErrNoRows if
+// there is no such record
+type Store interface {
+	Close() error
+
+	CreateRequest(rdb *models.RequestDB) error
+	GetRequests(query models.HistoryQuery) (*models.RequestsDB, error)
+	GetRequest(id int32) (*models.RequestDB, error)
+	SetRequestTags(id int32, tags models.Tags) error
+	DeleteRequest(id int32) error
+	DeleteRequests(projectID int) error
+
+	CreateResponse(resp *models.ResponseDB) error
+	GetResponse(requestID int32) (*models.ResponseDB, error)
+	GetResponses(requestIDs []int) (map[int]*models.ResponseDB, error)
+
+	CreateWebSocketMessage(message *models.WebSocketMessage) error
+	GetWebSocketMessages(requestID int32) (*models.WebSocketMessages, error)
+
+	GetInterceptSettings(projectID int) (*models.InterceptSettings, error)
+	UpdateInterceptSettings(settings *models.InterceptSettings) error
+	CreateInterceptItem(item *models.InterceptItem) error
+	GetInterceptItem(id int32) (*models.InterceptItem, error)
+	GetInterceptItems(projectID int) (*models.InterceptItems, error)
+	DecideInterceptItem(id int32, state, modified string) error
+	DeleteInterceptItem(id int32) error
+
+	CreateRule(rule *models.RuleDB) error
+	UpdateRule(rule *models.RuleDB) error
+	GetRules(projectID int) (*models.RulesDB, error)
+	GetRule(id int32) (*models.RuleDB, error)
+	DeleteRule(id int32) error
+
+	CreateProject(project *models.ProjectDB) error
+	GetProjects() (*models.ProjectsDB, error)
+	GetProject(id int32) (*models.ProjectDB, error)
+	GetActiveProject() (*models.ProjectDB, error)
+	UpdateProject(project *models.ProjectDB) error
+	ActivateProject(id int32) error
+	DeleteProject(id int32) error
+}
+
+// ErrDefaultProject - default project can not be deleted as
+// traffic saved before projects belongs to it
+var ErrDefaultProject = errors.New("default project can not be deleted")
+
+var (
+	_ Store = (*DB)(nil)
+	_ Store = (*BoltDB)(nil)
+	_ Store = (*MemoryDB)(nil)
+)
+
+// kinds of store
+const (
+	StorePostgres = "postgres"
+	StoreBolt     = "bolt"
+	StoreMemory   = "memory"
+)
+
+// Config - which store to open and its settings
+type Config struct {
+	Kind     string
+	Postgres Settings
+	// BoltPath - file of bolt store
+	BoltPath string
+	// Capacity - how many requests memory store keeps
+	Capacity int
+}
+
+// ConfigFromEnv read config from environment:
+// PROXY_STORE - postgres (default), bolt or memory,
+// PROXY_STORE_PATH - file of bolt store, proxy.db by default,
+// PROXY_STORE_CAPACITY - how many requests memory store keeps, 10000 by default,
+// PROXY_DB_HOST, PROXY_DB_PORT, PROXY_DB_USER, PROXY_DB_PASSWORD,
+// PROXY_DB_NAME - connection to postgres, defaults are for docker-compose
+func ConfigFromEnv() Config {
+	capacity, err := strconv.Atoi(env("PROXY_STORE_CAPACITY", "10000"))
+	if err != nil {
+		// invalid capacity is reported by Open
+		capacity = 0
+	}
+	return Config{
+		Kind: env("PROXY_STORE", StorePostgres),
+		Postgres: Settings{
+			User:     env("PROXY_DB_USER", "classic"),
+			Password: env("PROXY_DB_PASSWORD", "nopassword"),
+			Addr:     env("PROXY_DB_HOST", "proxy-db"),
+			Port:     ":" + env("PROXY_DB_PORT", "5432"),
+			Db:       env("PROXY_DB_NAME", "proxybase"),
+			MaxOpen:  20,
+			MaxIdle:  20,
+			TTL:      time.Hour,
+		},
+		BoltPath: env("PROXY_STORE_PATH", "proxy.db"),
+		Capacity: capacity,
+	}
+}
+
+func env(name, byDefault string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return byDefault
+}
+
+// Open open store of config kind
+func Open(config Config) (Store, error) {
+	switch config.Kind {
+	case StorePostgres:
+		return openPostgres(config.Postgres)
+	case StoreBolt:
+		return OpenBolt(config.BoltPath)
+	case StoreMemory:
+		return NewMemory(config.Capacity)
+	}
+	return nil, errors.New("unknown store " + config.Kind + ", must be postgres, bolt or memory")
+}
+
+// openPostgres connect to postgres and apply pending migrations
+func openPostgres(settings Settings) (*DB, error) {
+	db, err := Init(settings)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := db.Migrate(false)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	for _, migration := range applied {
+		log.Printf("Applied migration %d: %s", migration.Version, migration.Name)
+	}
+	return db, nil
+}
+
+// historyPage collect page of history from requests going in order
+// of query, for stores that can not run queries
+type historyPage struct {
+	query    models.HistoryQuery
+	requests []models.RequestDB
+	total    int
+}
+
+// add take request to the page, if it matches query
+func (page *historyPage) add(request *models.RequestDB, responses []models.ResponseDB) {
+	if page.query.Project > 0 && request.ProjectID != page.query.Project {
+		return
+	}
+	if !page.query.Filter.Match(request, responses) {
+		return
+	}
+	page.total++
+	if (page.query.AfterID > 0 && request.ID <= page.query.AfterID) ||
+		(page.query.BeforeID > 0 && request.ID >= page.query.BeforeID) {
+		return
+	}
+	// one more request shows if there is the next page
+	if len(page.requests) <= page.query.Limit {
+		page.requests = append(page.requests, *request)
+	}
+}
+
+func (page *historyPage) result() *models.RequestsDB {
+	var (
+		requestsDB = &models.RequestsDB{Total: page.total}
+		requests   = page.requests
+	)
+	if requests == nil {
+		requests = make([]models.RequestDB, 0)
+	}
+	if len(requests) > page.query.Limit {
+		requests = requests[:page.query.Limit]
+		next := requests[len(requests)-1].ID
+		requestsDB.Next = &next
+	}
+	requestsDB.Requests = requests
+	return requestsDB
+}
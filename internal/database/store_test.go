@@ -0,0 +1,211 @@
+package database
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+// stores return stores, that can be tested without database
+// server. Queries of postgres store are checked by filter tests
+func stores(t *testing.T) map[string]Store {
+	t.Helper()
+	bolt, err := OpenBolt(filepath.Join(t.TempDir(), "proxy.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	memory, err := NewMemory(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		bolt.Close()
+		memory.Close()
+	})
+	return map[string]Store{StoreBolt: bolt, StoreMemory: memory}
+}
+
+// fillHistory add requests 1-4 to default project and 5 to project 2
+func fillHistory(t *testing.T, db Store) {
+	t.Helper()
+	var (
+		json = models.Header{{Name: "Content-Type", Value: "application/json"}}
+		html = models.Header{{Name: "Content-Type", Value: "text/html; charset=utf-8"}}
+		from = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	)
+	project := &models.ProjectDB{Name: "other"}
+	if err := db.CreateProject(project); err != nil {
+		t.Fatal(err)
+	}
+	requests := []struct {
+		request  models.RequestDB
+		response *models.ResponseDB
+		tags     models.Tags
+	}{
+		{
+			models.RequestDB{Method: "GET", Scheme: "https", RemoteAddr: "mail.ru:443", Path: "/api/a"},
+			&models.ResponseDB{Status: 200, Header: json, Body: []byte(`{"ok": true}`)},
+			models.Tags{"auth", "api"},
+		},
+		{
+			models.RequestDB{Method: "POST", Scheme: "http", RemoteAddr: "example.com", Path: "/login",
+				Body: []byte("user=admin")},
+			&models.ResponseDB{Status: 302},
+			nil,
+		},
+		{
+			models.RequestDB{Method: "GET", Scheme: "https", RemoteAddr: "m.mail.ru", Path: "/api/b"},
+			&models.ResponseDB{Status: 404, Header: html, Body: []byte("not found")},
+			models.Tags{"api"},
+		},
+		{
+			models.RequestDB{Method: "PUT", Scheme: "https", RemoteAddr: "notmail.ru", Path: "/api_c"},
+			nil,
+			nil,
+		},
+		{
+			models.RequestDB{Method: "GET", Scheme: "https", RemoteAddr: "mail.ru", Path: "/other",
+				ProjectID: project.ID},
+			&models.ResponseDB{Status: 200},
+			nil,
+		},
+	}
+	for i, r := range requests {
+		request := r.request
+		request.Add = from.Add(time.Duration(i) * time.Hour)
+		request.URL = request.TargetURL()
+		if err := db.CreateRequest(&request); err != nil {
+			t.Fatal(err)
+		}
+		if request.ID != i+1 {
+			t.Fatalf("request has id %d, want %d", request.ID, i+1)
+		}
+		if r.response != nil {
+			r.response.RequestID = request.ID
+			if err := db.CreateResponse(r.response); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if r.tags != nil {
+			if err := db.SetRequestTags(int32(request.ID), r.tags); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func ids(requestsDB *models.RequestsDB) []int {
+	ids := make([]int, 0, len(requestsDB.Requests))
+	for _, request := range requestsDB.Requests {
+		ids = append(ids, request.ID)
+	}
+	return ids
+}
+
+func TestGetRequestsFilter(t *testing.T) {
+	var (
+		from = time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC)
+		to   = from.Add(2 * time.Hour)
+	)
+	tests := []struct {
+		name   string
+		filter models.HistoryFilter
+		want   []int
+	}{
+		{"empty", models.HistoryFilter{}, []int{1, 2, 3, 4}},
+		{"scheme", models.HistoryFilter{Scheme: "http"}, []int{2}},
+		{"host contains", models.HistoryFilter{Host: "MAIL.ru"}, []int{1, 3, 4}},
+		{"host suffix", models.HistoryFilter{Host: "mail.ru", HostMatch: models.HostSuffix}, []int{1, 3}},
+		{"host exact without port", models.HistoryFilter{Host: "mail.ru", HostMatch: models.HostExact}, []int{1}},
+		{"path prefix", models.HistoryFilter{PathPrefix: "/api/"}, []int{1, 3}},
+		{"path prefix with underscore", models.HistoryFilter{PathPrefix: "/api_"}, []int{4}},
+		{"methods", models.HistoryFilter{Methods: []string{"get", "PUT"}}, []int{1, 3, 4}},
+		{"status class", models.HistoryFilter{Status: []models.StatusRange{{From: 200, To: 299}}}, []int{1}},
+		{"status ranges", models.HistoryFilter{Status: []models.StatusRange{{From: 300, To: 399}, {From: 404, To: 404}}}, []int{2, 3}},
+		{"time", models.HistoryFilter{From: &from, To: &to}, []int{2, 3}},
+		{"request body", models.HistoryFilter{BodyContains: "admin"}, []int{2}},
+		{"response body", models.HistoryFilter{BodyContains: `"ok"`}, []int{1}},
+		{"header line", models.HistoryFilter{HeaderContains: "content-type: TEXT/html"}, []int{3}},
+		{"content type", models.HistoryFilter{ContentType: "application/"}, []int{1}},
+		{"tags", models.HistoryFilter{Tags: []string{"api", "auth"}}, []int{1}},
+		{"text of url", models.HistoryFilter{Text: "EXAMPLE.com/login"}, []int{2}},
+		{"text of response", models.HistoryFilter{Text: "not found"}, []int{3}},
+		{"all", models.HistoryFilter{
+			All: []models.HistoryFilter{{PathPrefix: "/api"}, {Methods: []string{"GET"}}},
+		}, []int{1, 3}},
+		{"any", models.HistoryFilter{
+			Any: []models.HistoryFilter{{Methods: []string{"PUT"}}, {Status: []models.StatusRange{{From: 200, To: 299}}}},
+		}, []int{1, 4}},
+		{"conditions and any", models.HistoryFilter{
+			Scheme: "https",
+			Any:    []models.HistoryFilter{{Methods: []string{"POST"}}, {Tags: []string{"api"}}},
+		}, []int{1, 3}},
+		{"nothing", models.HistoryFilter{Host: "yandex.ru"}, []int{}},
+	}
+	for name, db := range stores(t) {
+		fillHistory(t, db)
+		for _, test := range tests {
+			t.Run(name+"/"+test.name, func(t *testing.T) {
+				requestsDB, err := db.GetRequests(models.HistoryQuery{
+					Filter:  test.filter,
+					Limit:   10,
+					Project: models.DefaultProject,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := ids(requestsDB); !reflect.DeepEqual(got, test.want) {
+					t.Errorf("got requests %v, want %v", got, test.want)
+				}
+				if requestsDB.Total != len(test.want) || requestsDB.Next != nil {
+					t.Errorf("got total %d and next %v, want total %d without next",
+						requestsDB.Total, requestsDB.Next, len(test.want))
+				}
+			})
+		}
+	}
+}
+
+func TestGetRequestsPages(t *testing.T) {
+	tests := []struct {
+		name  string
+		query models.HistoryQuery
+		want  []int
+		next  int
+		total int
+	}{
+		{"first page", models.HistoryQuery{Limit: 2}, []int{1, 2}, 2, 4},
+		{"last page", models.HistoryQuery{Limit: 2, AfterID: 2}, []int{3, 4}, 0, 4},
+		{"page of limit size", models.HistoryQuery{Limit: 4}, []int{1, 2, 3, 4}, 0, 4},
+		{"newer first", models.HistoryQuery{Limit: 3, Desc: true}, []int{4, 3, 2}, 2, 4},
+		{"newer first next page", models.HistoryQuery{Limit: 3, Desc: true, BeforeID: 2}, []int{1}, 0, 4},
+		{"between cursors", models.HistoryQuery{Limit: 10, AfterID: 1, BeforeID: 4}, []int{2, 3}, 0, 4},
+		{"filtered page", models.HistoryQuery{Limit: 1, Filter: models.HistoryFilter{Methods: []string{"GET"}}}, []int{1}, 1, 2},
+		{"filtered next page", models.HistoryQuery{Limit: 1, AfterID: 1, Filter: models.HistoryFilter{Methods: []string{"GET"}}}, []int{3}, 0, 2},
+	}
+	for name, db := range stores(t) {
+		fillHistory(t, db)
+		for _, test := range tests {
+			t.Run(name+"/"+test.name, func(t *testing.T) {
+				test.query.Project = models.DefaultProject
+				requestsDB, err := db.GetRequests(test.query)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := ids(requestsDB); !reflect.DeepEqual(got, test.want) {
+					t.Errorf("got requests %v, want %v", got, test.want)
+				}
+				var next int
+				if requestsDB.Next != nil {
+					next = *requestsDB.Next
+				}
+				if next != test.next || requestsDB.Total != test.total {
+					t.Errorf("got next %d and total %d, want %d and %d", next, requestsDB.Total, test.next, test.total)
+				}
+			})
+		}
+	}
+}
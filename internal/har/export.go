@@ -0,0 +1,178 @@
+package har
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+// truncatedComment - comment of request or response, which body
+// was saved truncated
+const truncatedComment = "body is truncated"
+
+var creator = Creator{
+	Name:    "SecurityProxyServer",
+	Version: "1.0",
+}
+
+// Export make archive of requests with their responses. Responses
+// must be set to Response field of requests
+func Export(requests []models.RequestDB) *HAR {
+	entries := make([]Entry, 0, len(requests))
+	for i := range requests {
+		entries = append(entries, exportEntry(&requests[i]))
+	}
+	return &HAR{Log: Log{
+		Version: Version,
+		Creator: creator,
+		Entries: entries,
+	}}
+}
+
+func exportEntry(rdb *models.RequestDB) Entry {
+	var entry = Entry{
+		StartedDateTime: rdb.Add.Format(time.RFC3339Nano),
+		Request:         exportRequest(rdb),
+	}
+	if rdb.Response == nil {
+		// browsers export failed requests the same way
+		entry.Response = Response{
+			HTTPVersion: httpVersion(rdb.Proto),
+			Cookies:     []Cookie{},
+			Headers:     []NameValue{},
+			Content:     Content{MimeType: "x-unknown"},
+			HeadersSize: -1,
+			BodySize:    -1,
+			Comment:     "no response",
+		}
+		return entry
+	}
+	entry.Response = exportResponse(rdb.Response)
+	entry.Time = float64(rdb.Response.Duration)
+	// only the whole time is known
+	entry.Timings = Timings{Wait: entry.Time}
+	return entry
+}
+
+func exportRequest(rdb *models.RequestDB) Request {
+	var request = Request{
+		Method:      rdb.Method,
+		URL:         rdb.TargetURL(),
+		HTTPVersion: httpVersion(rdb.Proto),
+		Cookies:     exportCookies((&http.Request{Header: rdb.Header.HTTP()}).Cookies()),
+		Headers:     exportHeader(rdb.Header),
+		QueryString: exportQuery(rdb.RawQuery),
+		HeadersSize: -1,
+		BodySize:    int64(len(rdb.Body)),
+	}
+	if len(rdb.Body) > 0 {
+		request.PostData = &PostData{MimeType: rdb.Header.Get("Content-Type")}
+		request.PostData.Text, request.PostData.Encoding = exportBody(rdb.Body)
+		if strings.HasPrefix(request.PostData.MimeType, "application/x-www-form-urlencoded") {
+			request.PostData.Params = exportQuery(string(rdb.Body))
+		}
+	}
+	if rdb.Truncated {
+		request.Comment = truncatedComment
+	}
+	return request
+}
+
+func exportResponse(respDB *models.ResponseDB) Response {
+	var response = Response{
+		Status:      respDB.Status,
+		StatusText:  http.StatusText(respDB.Status),
+		HTTPVersion: httpVersion(respDB.Proto),
+		Cookies:     exportCookies((&http.Response{Header: respDB.Header.HTTP()}).Cookies()),
+		Headers:     exportHeader(respDB.Header),
+		RedirectURL: respDB.Header.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    respDB.Size,
+		Content:     Content{MimeType: respDB.Header.Get("Content-Type")},
+	}
+
+	body := respDB.Body
+	if encoding := respDB.Header.Get("Content-Encoding"); encoding != "" {
+		if decoded, err := models.DecodeBody(body, encoding); err == nil {
+			response.Content.Compression = int64(len(decoded) - len(body))
+			body = decoded
+		}
+	}
+	response.Content.Size = int64(len(body))
+	response.Content.Text, response.Content.Encoding = exportBody(body)
+	if respDB.Truncated {
+		response.Comment = truncatedComment
+	}
+	return response
+}
+
+// exportBody return body as text or, if it is binary, as base64
+func exportBody(body []byte) (text, encoding string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+	return base64.StdEncoding.EncodeToString(body), "base64"
+}
+
+func exportHeader(header models.Header) []NameValue {
+	var headers = make([]NameValue, 0, len(header))
+	for _, field := range header {
+		headers = append(headers, NameValue{Name: field.Name, Value: field.Value})
+	}
+	return headers
+}
+
+// exportQuery split query to parameters keeping their order
+func exportQuery(query string) []NameValue {
+	var params = make([]NameValue, 0)
+	for _, pair := range strings.Split(query, "&") {
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		var param = NameValue{Name: unescape(kv[0])}
+		if len(kv) == 2 {
+			param.Value = unescape(kv[1])
+		}
+		params = append(params, param)
+	}
+	return params
+}
+
+func unescape(s string) string {
+	if unescaped, err := url.QueryUnescape(s); err == nil {
+		return unescaped
+	}
+	return s
+}
+
+func exportCookies(cookies []*http.Cookie) []Cookie {
+	var exported = make([]Cookie, 0, len(cookies))
+	for _, cookie := range cookies {
+		var c = Cookie{
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Path:     cookie.Path,
+			Domain:   cookie.Domain,
+			HTTPOnly: cookie.HttpOnly,
+			Secure:   cookie.Secure,
+		}
+		if !cookie.Expires.IsZero() {
+			c.Expires = cookie.Expires.Format(time.RFC3339)
+		}
+		exported = append(exported, c)
+	}
+	return exported
+}
+
+func httpVersion(proto string) string {
+	if proto == "" {
+		return "HTTP/1.1"
+	}
+	return proto
+}
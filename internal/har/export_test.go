@@ -0,0 +1,127 @@
+package har
+
+import (
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+func gzipped(t *testing.T, body string) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	w := gzip.NewWriter(&buffer)
+	if _, err := w.Write([]byte(body)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func TestExportRequest(t *testing.T) {
+	rdb := models.RequestDB{
+		Method:   "POST",
+		URL:      "https://mail.ru/login?next=%2Fa&x",
+		RawQuery: "next=%2Fa&x",
+		Header: models.Header{
+			{Name: "Host", Value: "mail.ru"},
+			{Name: "Cookie", Value: "a=1; b=2"},
+			{Name: "Content-Type", Value: "application/x-www-form-urlencoded"},
+		},
+		Body:      []byte("user=a+b&password=%26"),
+		Truncated: true,
+		Add:       time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+	entry := Export([]models.RequestDB{rdb}).Log.Entries[0]
+	request := entry.Request
+
+	if entry.StartedDateTime != "2020-05-01T12:00:00Z" || request.Method != "POST" ||
+		request.URL != rdb.URL || request.HTTPVersion != "HTTP/1.1" || request.BodySize != 21 {
+		t.Errorf("exported entry %+v", entry)
+	}
+	if want := []NameValue{{Name: "next", Value: "/a"}, {Name: "x", Value: ""}}; !reflect.DeepEqual(request.QueryString, want) {
+		t.Errorf("query string %v, want %v", request.QueryString, want)
+	}
+	if want := []Cookie{{Name: "a", Value: "1"}, {Name: "b", Value: "2"}}; !reflect.DeepEqual(request.Cookies, want) {
+		t.Errorf("cookies %v, want %v", request.Cookies, want)
+	}
+	if len(request.Headers) != 3 || request.Headers[1].Name != "Cookie" {
+		t.Errorf("headers %v are not in the order of request", request.Headers)
+	}
+	if request.PostData == nil || request.PostData.Text != "user=a+b&password=%26" ||
+		!reflect.DeepEqual(request.PostData.Params, []NameValue{{Name: "user", Value: "a b"}, {Name: "password", Value: "&"}}) {
+		t.Errorf("post data %+v", request.PostData)
+	}
+	if request.Comment != truncatedComment {
+		t.Errorf("truncated request has comment %q", request.Comment)
+	}
+	if entry.Response.Status != 0 || entry.Response.BodySize != -1 || entry.Response.Comment == "" {
+		t.Errorf("request without response is exported with response %+v", entry.Response)
+	}
+}
+
+func TestExportResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		response models.ResponseDB
+		want     Content
+	}{
+		{
+			name: "text",
+			response: models.ResponseDB{Status: 200,
+				Header: models.Header{{Name: "Content-Type", Value: "text/plain"}}, Body: []byte("hello"), Size: 5},
+			want: Content{Size: 5, MimeType: "text/plain", Text: "hello"},
+		},
+		{
+			name: "gzip is decoded",
+			response: models.ResponseDB{Status: 200,
+				Header: models.Header{{Name: "Content-Encoding", Value: "gzip"}}, Body: gzipped(t, "hello, hello")},
+			want: Content{Size: 12, Compression: 12 - int64(len(gzipped(t, "hello, hello"))), Text: "hello, hello"},
+		},
+		{
+			name:     "binary in base64",
+			response: models.ResponseDB{Status: 200, Body: []byte{0x89, 'P', 'N', 'G'}},
+			want:     Content{Size: 4, Text: "iVBORw==", Encoding: "base64"},
+		},
+		{
+			name:     "unknown encoding is kept",
+			response: models.ResponseDB{Status: 200, Header: models.Header{{Name: "Content-Encoding", Value: "zstd"}}, Body: []byte("x")},
+			want:     Content{Size: 1, Text: "x"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := test.response
+			entry := Export([]models.RequestDB{{Method: "GET", URL: "http://mail.ru/", Response: &response}}).Log.Entries[0]
+			if !reflect.DeepEqual(entry.Response.Content, test.want) {
+				t.Errorf("content %+v, want %+v", entry.Response.Content, test.want)
+			}
+			if entry.Response.Status != 200 || entry.Response.StatusText != "OK" || entry.Response.BodySize != test.response.Size {
+				t.Errorf("exported response %+v", entry.Response)
+			}
+		})
+	}
+}
+
+func TestExportRedirect(t *testing.T) {
+	rdb := models.RequestDB{Method: "GET", URL: "http://mail.ru/", Response: &models.ResponseDB{
+		Status:    302,
+		Header:    models.Header{{Name: "Location", Value: "/home"}, {Name: "Set-Cookie", Value: "s=1; Path=/; HttpOnly"}},
+		Duration:  15,
+		Truncated: true,
+	}}
+	entry := Export([]models.RequestDB{rdb}).Log.Entries[0]
+	if entry.Response.RedirectURL != "/home" || entry.Time != 15 || entry.Timings.Wait != 15 ||
+		entry.Response.Comment != truncatedComment {
+		t.Errorf("exported entry %+v", entry)
+	}
+	want := []Cookie{{Name: "s", Value: "1", Path: "/", HTTPOnly: true}}
+	if !reflect.DeepEqual(entry.Response.Cookies, want) {
+		t.Errorf("response cookies %v, want %v", entry.Response.Cookies, want)
+	}
+}
@@ -0,0 +1,133 @@
+package har
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+// ToRequest make request of history from entry. Its response is set
+// to Response field, if the entry has it
+func (entry *Entry) ToRequest() (*models.RequestDB, error) {
+	target, err := url.Parse(entry.Request.URL)
+	if err != nil {
+		return nil, err
+	}
+	if (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, errors.New("url must be absolute http or https url")
+	}
+	if entry.Request.Method == "" {
+		return nil, errors.New("method is required")
+	}
+
+	var rdb = &models.RequestDB{
+		Method:     strings.ToUpper(entry.Request.Method),
+		Scheme:     target.Scheme,
+		RemoteAddr: target.Host,
+		Path:       target.EscapedPath(),
+		RawQuery:   target.RawQuery,
+		Proto:      importVersion(entry.Request.HTTPVersion),
+		Header:     importHeader(entry.Request.Headers),
+		Truncated:  entry.Request.Comment == truncatedComment,
+	}
+	if target.User != nil {
+		rdb.UserLogin = target.User.Username()
+		rdb.UserPassword, _ = target.User.Password()
+		target.User = nil
+	}
+	target.Fragment = ""
+	rdb.URL = target.String()
+	if started, err := time.Parse(time.RFC3339Nano, entry.StartedDateTime); err == nil {
+		rdb.Add = started
+	}
+	if rdb.Header.Get("Host") == "" {
+		rdb.Header = append(models.Header{{Name: "Host", Value: target.Host}}, rdb.Header...)
+	}
+
+	if postData := entry.Request.PostData; postData != nil {
+		if postData.Text == "" && len(postData.Params) > 0 {
+			form := make([]string, 0, len(postData.Params))
+			for _, param := range postData.Params {
+				form = append(form, url.QueryEscape(param.Name)+"="+url.QueryEscape(param.Value))
+			}
+			rdb.Body = []byte(strings.Join(form, "&"))
+		} else if rdb.Body, err = importBody(postData.Text, postData.Encoding); err != nil {
+			return nil, errors.New("request body: " + err.Error())
+		}
+	}
+
+	// status 0 means there was no response
+	if entry.Response.Status > 0 {
+		if rdb.Response, err = entry.response(); err != nil {
+			return nil, err
+		}
+	}
+	return rdb, nil
+}
+
+func (entry *Entry) response() (*models.ResponseDB, error) {
+	var (
+		response = &entry.Response
+		respDB   = &models.ResponseDB{
+			Proto:     importVersion(response.HTTPVersion),
+			Status:    response.Status,
+			Header:    importHeader(response.Headers),
+			Duration:  int64(entry.Time),
+			Truncated: response.Comment == truncatedComment,
+		}
+		err error
+	)
+	if respDB.Body, err = importBody(response.Content.Text, response.Content.Encoding); err != nil {
+		return nil, errors.New("response body: " + err.Error())
+	}
+	// content of archive is decoded, so the stored
+	// body is not compressed any more
+	if len(respDB.Body) > 0 {
+		respDB.Header = respDB.Header.Del("Content-Encoding")
+	}
+	respDB.Size = int64(len(respDB.Body))
+	if response.Content.Size > respDB.Size {
+		respDB.Size = response.Content.Size
+		respDB.Truncated = true
+	}
+	if started, err := time.Parse(time.RFC3339Nano, entry.StartedDateTime); err == nil {
+		respDB.Add = started.Add(time.Duration(entry.Time * float64(time.Millisecond)))
+	}
+	return respDB, nil
+}
+
+func importBody(text, encoding string) ([]byte, error) {
+	if encoding == "base64" {
+		return base64.StdEncoding.DecodeString(text)
+	}
+	return []byte(text), nil
+}
+
+// importHeader make header without pseudo headers of HTTP/2
+func importHeader(headers []NameValue) models.Header {
+	var header = make(models.Header, 0, len(headers))
+	for _, h := range headers {
+		if strings.HasPrefix(h.Name, ":") {
+			continue
+		}
+		header = append(header, models.HeaderField{Name: h.Name, Value: h.Value})
+	}
+	return header
+}
+
+// importVersion make protocol of request from httpVersion of archive.
+// HTTP/2 and HTTP/3 requests are sent again over HTTP/2
+func importVersion(version string) string {
+	switch strings.ToUpper(version) {
+	case "HTTP/1.0":
+		return "HTTP/1.0"
+	case "", "HTTP/1.1":
+		return "HTTP/1.1"
+	default:
+		return "HTTP/2.0"
+	}
+}
@@ -0,0 +1,150 @@
+package har
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+// TestRoundTrip export requests to archive, write it as json
+// and import requests back
+func TestRoundTrip(t *testing.T) {
+	var (
+		add  = time.Date(2020, 5, 1, 12, 0, 0, 123000000, time.UTC)
+		host = models.HeaderField{Name: "Host", Value: "mail.ru"}
+	)
+	tests := []struct {
+		name     string
+		request  models.RequestDB
+		response *models.ResponseDB
+		// want - response after import, the exported one by default
+		want *models.ResponseDB
+	}{
+		{
+			name: "get with response",
+			request: models.RequestDB{Method: "GET", URL: "https://mail.ru/a%20b?q=1&q=2", Proto: "HTTP/1.1",
+				Header: models.Header{host, {Name: "Cookie", Value: "a=1; b=2"}}},
+			response: &models.ResponseDB{Proto: "HTTP/1.1", Status: 200, Duration: 15,
+				Header: models.Header{{Name: "Content-Type", Value: "text/plain"}}, Body: []byte("hello"), Size: 5},
+		},
+		{
+			name: "compressed response is decoded",
+			request: models.RequestDB{Method: "GET", URL: "https://mail.ru/", Proto: "HTTP/2.0",
+				Header: models.Header{host}},
+			response: &models.ResponseDB{Proto: "HTTP/2.0", Status: 200,
+				Header: models.Header{{Name: "Content-Encoding", Value: "gzip"}, {Name: "x-Trace", Value: "1"}},
+				Body:   gzipped(t, "hello, hello"), Size: 32},
+			want: &models.ResponseDB{Proto: "HTTP/2.0", Status: 200,
+				Header: models.Header{{Name: "x-Trace", Value: "1"}}, Body: []byte("hello, hello"), Size: 12},
+		},
+		{
+			name: "form",
+			request: models.RequestDB{Method: "POST", URL: "http://mail.ru/login", Proto: "HTTP/1.1",
+				Header: models.Header{host, {Name: "Content-Type", Value: "application/x-www-form-urlencoded"}},
+				Body:   []byte("user=a+b&password=%26")},
+			response: &models.ResponseDB{Proto: "HTTP/1.1", Status: 302,
+				Header: models.Header{{Name: "Location", Value: "/"}}, Body: []byte{}},
+		},
+		{
+			name: "binary body",
+			request: models.RequestDB{Method: "PUT", URL: "http://mail.ru/file", Proto: "HTTP/1.0",
+				Header: models.Header{host, {Name: "Content-Type", Value: "application/octet-stream"}},
+				Body:   []byte{0, 0xff, '\r', '\n'}},
+			response: &models.ResponseDB{Proto: "HTTP/1.0", Status: 201,
+				Header: models.Header{}, Body: []byte{0x89, 'P', 'N', 'G'}, Size: 4},
+		},
+		{
+			name: "truncated without response",
+			request: models.RequestDB{Method: "POST", URL: "http://mail.ru/upload", Proto: "HTTP/1.1",
+				Header: models.Header{host}, Body: []byte("part"), Truncated: true},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			original := test.request
+			original.Add = add
+			original.Response = test.response
+
+			data, err := json.Marshal(Export([]models.RequestDB{original}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var archive HAR
+			if err = json.Unmarshal(data, &archive); err != nil {
+				t.Fatal(err)
+			}
+			if len(archive.Log.Entries) != 1 {
+				t.Fatalf("archive has %d entries", len(archive.Log.Entries))
+			}
+			rdb, err := archive.Log.Entries[0].ToRequest()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if rdb.Method != original.Method || rdb.URL != original.URL || rdb.Proto != original.Proto ||
+				!bytes.Equal(rdb.Body, original.Body) || rdb.Truncated != original.Truncated || !rdb.Add.Equal(add) {
+				t.Errorf("imported request\n%+v\nwant\n%+v", rdb, original)
+			}
+			if !equalHeader(rdb.Header, original.Header) {
+				t.Errorf("header %v, want %v", rdb.Header, original.Header)
+			}
+
+			want := test.want
+			if want == nil {
+				want = test.response
+			}
+			if want == nil {
+				if rdb.Response != nil {
+					t.Errorf("request has response %+v", rdb.Response)
+				}
+				return
+			}
+			got := rdb.Response
+			if got == nil {
+				t.Fatal("response is lost")
+			}
+			if got.Status != want.Status || got.Proto != want.Proto || !bytes.Equal(got.Body, want.Body) ||
+				got.Size != want.Size || got.Duration != want.Duration || got.Truncated {
+				t.Errorf("imported response\n%+v\nwant\n%+v", got, want)
+			}
+			if !equalHeader(got.Header, want.Header) {
+				t.Errorf("response header %v, want %v", got.Header, want.Header)
+			}
+		})
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry Entry
+	}{
+		{"relative url", Entry{Request: Request{Method: "GET", URL: "/a"}}},
+		{"ftp url", Entry{Request: Request{Method: "GET", URL: "ftp://mail.ru/"}}},
+		{"no method", Entry{Request: Request{URL: "http://mail.ru/"}}},
+		{"broken base64", Entry{Request: Request{Method: "POST", URL: "http://mail.ru/",
+			PostData: &PostData{Text: "!", Encoding: "base64"}}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := test.entry.ToRequest(); err == nil {
+				t.Error("entry is imported")
+			}
+		})
+	}
+}
+
+func equalHeader(a, b models.Header) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
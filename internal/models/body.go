@@ -0,0 +1,51 @@
+package models
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// DecodeBody undo Content-Encoding of body. Encodings are listed in
+// the order they were applied, so they are removed from the last one.
+// On error the bytes decoded so far are returned
+func DecodeBody(body []byte, contentEncoding string) ([]byte, error) {
+	encodings := strings.Split(contentEncoding, ",")
+	for i := len(encodings) - 1; i >= 0; i-- {
+		var reader io.Reader
+		switch encoding := strings.ToLower(strings.TrimSpace(encodings[i])); encoding {
+		case "", "identity":
+			continue
+		case "gzip", "x-gzip":
+			gz, err := gzip.NewReader(bytes.NewReader(body))
+			if err != nil {
+				return nil, err
+			}
+			reader = gz
+		case "deflate":
+			// deflate must be zlib stream, but some servers send raw deflate
+			if zr, err := zlib.NewReader(bytes.NewReader(body)); err == nil {
+				reader = zr
+			} else {
+				reader = flate.NewReader(bytes.NewReader(body))
+			}
+		case "br":
+			reader = brotli.NewReader(bytes.NewReader(body))
+		default:
+			return nil, errors.New("unsupported content encoding " + encoding)
+		}
+		decoded, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return decoded, err
+		}
+		body = decoded
+	}
+	return body, nil
+}
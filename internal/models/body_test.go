@@ -0,0 +1,96 @@
+package models
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func compress(t *testing.T, body []byte, newWriter func(io.Writer) io.WriteCloser) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	w := newWriter(&buffer)
+	if _, err := w.Write(body); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func TestDecodeBody(t *testing.T) {
+	var (
+		body    = []byte(`{"message": "hello, hello, hello"}`)
+		gzipped = compress(t, body, func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) })
+		zlibbed = compress(t, body, func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) })
+		flated  = compress(t, body, func(w io.Writer) io.WriteCloser {
+			fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+			return fw
+		})
+		brotlied = compress(t, body, func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) })
+		// br applied to gzipped body
+		chained = compress(t, gzipped, func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) })
+	)
+
+	tests := []struct {
+		name     string
+		body     []byte
+		encoding string
+		wantErr  bool
+	}{
+		{"identity", body, "", false},
+		{"identity named", body, "identity", false},
+		{"gzip", gzipped, "gzip", false},
+		{"x-gzip", gzipped, "x-gzip", false},
+		{"deflate zlib", zlibbed, "deflate", false},
+		{"deflate raw", flated, "deflate", false},
+		{"br", brotlied, "br", false},
+		{"br upper case", brotlied, "BR", false},
+		{"gzip then br", chained, "gzip, br", false},
+		{"unsupported", body, "compress", true},
+		{"broken gzip", body, "gzip", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decoded, err := DecodeBody(test.body, test.encoding)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("DecodeBody() error = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeBody() error = %v", err)
+			}
+			if !bytes.Equal(decoded, body) {
+				t.Errorf("DecodeBody() = %q, want %q", decoded, body)
+			}
+		})
+	}
+}
+
+func TestDecodeBodyTruncated(t *testing.T) {
+	body := bytes.Repeat([]byte("truncated body "), 1000)
+	for name, newWriter := range map[string]func(io.Writer) io.WriteCloser{
+		"gzip": func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		"br":   func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			encoded := compress(t, body, newWriter)
+			decoded, err := DecodeBody(encoded[:len(encoded)/2], name)
+			// GetBody sends partly decoded truncated bodies on this error
+			if err != io.ErrUnexpectedEOF {
+				t.Fatalf("DecodeBody() error = %v, want %v", err, io.ErrUnexpectedEOF)
+			}
+			if !bytes.HasPrefix(body, decoded) {
+				t.Errorf("DecodeBody() returned bytes, that are not the beginning of body")
+			}
+		})
+	}
+}
@@ -0,0 +1,316 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// how Host of HistoryFilter is compared with the host of request
+const (
+	// HostContains - host has Host inside, it is the default
+	HostContains = "contains"
+	// HostExact - host is Host
+	HostExact = "exact"
+	// HostSuffix - host is Host or its subdomain
+	HostSuffix = "suffix"
+)
+
+// HistoryFilter - conditions for requests of history. Set conditions
+// must be all true, then every filter of All must match and at least
+// one filter of Any, if Any is not empty. Empty filter matches all
+//easyjson:json
+type HistoryFilter struct {
+	Scheme    string `json:"scheme,omitempty"`
+	Host      string `json:"host,omitempty"`
+	HostMatch string `json:"host_match,omitempty"`
+	// PathPrefix - the path of request starts with it
+	PathPrefix string `json:"path_prefix,omitempty"`
+	// Methods - the method of request is one of them
+	Methods []string `json:"methods,omitempty"`
+	// Status - the status of response is in one of the ranges
+	Status []StatusRange `json:"status,omitempty"`
+	// From, To - the request was made in [From, To)
+	From *time.Time `json:"from,omitempty"`
+	To   *time.Time `json:"to,omitempty"`
+	// BodyContains - the body of request or response has these bytes
+	BodyContains string `json:"body_contains,omitempty"`
+	// HeaderContains - a "Name: value" line of request or response
+	// has this text, case insensitive
+	HeaderContains string `json:"header_contains,omitempty"`
+	// ContentType - Content-Type of response starts with it
+	ContentType string `json:"content_type,omitempty"`
+	// Tags - the request has all of them
+	Tags []string `json:"tags,omitempty"`
+	// Text - url, header lines or text body of request or response
+	// has this text, case insensitive
+	Text string `json:"text,omitempty"`
+
+	All []HistoryFilter `json:"all,omitempty"`
+	Any []HistoryFilter `json:"any,omitempty"`
+}
+
+// StatusRange - status codes from From to To including both
+//easyjson:json
+type StatusRange struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+// ParseStatusRange parse "200", "2xx" or "200-299"
+func ParseStatusRange(s string) (StatusRange, error) {
+	var (
+		status StatusRange
+		err    error
+	)
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch {
+	case len(s) == 3 && strings.HasSuffix(s, "xx"):
+		var class int
+		if class, err = strconv.Atoi(s[:1]); err == nil {
+			status = StatusRange{class * 100, class*100 + 99}
+		}
+	case strings.Contains(s, "-"):
+		bounds := strings.SplitN(s, "-", 2)
+		if status.From, err = strconv.Atoi(strings.TrimSpace(bounds[0])); err == nil {
+			status.To, err = strconv.Atoi(strings.TrimSpace(bounds[1]))
+		}
+	default:
+		if status.From, err = strconv.Atoi(s); err == nil {
+			status.To = status.From
+		}
+	}
+	if err != nil {
+		return status, errors.New("invalid status " + s)
+	}
+	return status, status.Validate()
+}
+
+// Validate check that the range has valid status codes
+func (status StatusRange) Validate() error {
+	if status.From < 100 || status.To > 999 || status.From > status.To {
+		return errors.New("status range must be within 100-999")
+	}
+	return nil
+}
+
+// Validate check the filter and its groups
+func (filter *HistoryFilter) Validate() error {
+	if filter.Scheme != "" && filter.Scheme != "http" && filter.Scheme != "https" {
+		return errors.New("scheme must be http or https")
+	}
+	switch filter.HostMatch {
+	case "", HostContains, HostExact, HostSuffix:
+	default:
+		return errors.New("host_match must be contains, exact or suffix")
+	}
+	for _, status := range filter.Status {
+		if err := status.Validate(); err != nil {
+			return err
+		}
+	}
+	for i := range filter.All {
+		if err := filter.All[i].Validate(); err != nil {
+			return err
+		}
+	}
+	for i := range filter.Any {
+		if err := filter.Any[i].Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Match check the request with its responses against the filter
+// the same way as the database does. It is used by stores, that
+// can not run queries
+func (filter *HistoryFilter) Match(rdb *RequestDB, responses []ResponseDB) bool {
+	if filter.Scheme != "" && rdb.Scheme != filter.Scheme {
+		return false
+	}
+	if filter.Host != "" && !filter.matchHost(rdb.RemoteAddr) {
+		return false
+	}
+	if !strings.HasPrefix(rdb.Path, filter.PathPrefix) {
+		return false
+	}
+	if len(filter.Methods) > 0 && !containsFold(filter.Methods, rdb.Method) {
+		return false
+	}
+	if len(filter.Status) > 0 && !anyResponse(responses, func(resp *ResponseDB) bool {
+		for _, status := range filter.Status {
+			if resp.Status >= status.From && resp.Status <= status.To {
+				return true
+			}
+		}
+		return false
+	}) {
+		return false
+	}
+	if filter.From != nil && rdb.Add.Before(*filter.From) {
+		return false
+	}
+	if filter.To != nil && !rdb.Add.Before(*filter.To) {
+		return false
+	}
+	if filter.BodyContains != "" {
+		body := []byte(filter.BodyContains)
+		if !bytes.Contains(rdb.Body, body) && !anyResponse(responses, func(resp *ResponseDB) bool {
+			return bytes.Contains(resp.Body, body)
+		}) {
+			return false
+		}
+	}
+	if filter.HeaderContains != "" {
+		line := strings.ToLower(filter.HeaderContains)
+		if !headerContains(rdb.Header, line) && !anyResponse(responses, func(resp *ResponseDB) bool {
+			return headerContains(resp.Header, line)
+		}) {
+			return false
+		}
+	}
+	if filter.ContentType != "" {
+		contentType := strings.ToLower(filter.ContentType)
+		if !anyResponse(responses, func(resp *ResponseDB) bool {
+			for _, field := range resp.Header {
+				if strings.EqualFold(field.Name, "Content-Type") && strings.HasPrefix(strings.ToLower(field.Value), contentType) {
+					return true
+				}
+			}
+			return false
+		}) {
+			return false
+		}
+	}
+	for _, tag := range filter.Tags {
+		if !rdb.Tags.Has(tag) {
+			return false
+		}
+	}
+	if filter.Text != "" {
+		text := strings.ToLower(filter.Text)
+		if !strings.Contains(strings.ToLower(rdb.Search), text) && !anyResponse(responses, func(resp *ResponseDB) bool {
+			return strings.Contains(strings.ToLower(resp.Search), text)
+		}) {
+			return false
+		}
+	}
+
+	for i := range filter.All {
+		if !filter.All[i].Match(rdb, responses) {
+			return false
+		}
+	}
+	if len(filter.Any) == 0 {
+		return true
+	}
+	for i := range filter.Any {
+		if filter.Any[i].Match(rdb, responses) {
+			return true
+		}
+	}
+	return false
+}
+
+func (filter *HistoryFilter) matchHost(address string) bool {
+	address = strings.ToLower(address)
+	host := strings.ToLower(filter.Host)
+	if filter.HostMatch == "" || filter.HostMatch == HostContains {
+		return strings.Contains(address, host)
+	}
+	// port is not a part of host
+	if colon := strings.LastIndex(address, ":"); colon >= 0 {
+		if _, err := strconv.Atoi(address[colon+1:]); err == nil {
+			address = address[:colon]
+		}
+	}
+	if filter.HostMatch == HostSuffix {
+		return address == host || strings.HasSuffix(address, "."+host)
+	}
+	return address == host
+}
+
+func anyResponse(responses []ResponseDB, check func(*ResponseDB) bool) bool {
+	for i := range responses {
+		if check(&responses[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// headerContains check if any "Name: value" line has lowercase line
+func headerContains(header Header, line string) bool {
+	for _, field := range header {
+		if strings.Contains(strings.ToLower(field.Name+": "+field.Value), line) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// HistoryQuery - filter of history with order and limit
+type HistoryQuery struct {
+	Filter HistoryFilter
+	// Desc - newer requests first
+	Desc  bool
+	Limit int
+	// AfterID, BeforeID - keyset cursor, only requests with id greater
+	// than AfterID and less than BeforeID are returned, if they are set
+	AfterID  int
+	BeforeID int
+	// Project - only requests of the project are returned, if it is set
+	Project int
+}
+
+// Tags - labels of request set by user.
+// They are stored in database as json array
+//easyjson:json
+type Tags []string
+
+// Value put tags to database as json
+func (tags Tags) Value() (driver.Value, error) {
+	if tags == nil {
+		tags = Tags{}
+	}
+	b, err := json.Marshal(tags)
+	return string(b), err
+}
+
+// Scan get tags from database json
+func (tags *Tags) Scan(src interface{}) error {
+	switch data := src.(type) {
+	case nil:
+		*tags = Tags{}
+		return nil
+	case []byte:
+		return json.Unmarshal(data, tags)
+	case string:
+		return json.Unmarshal([]byte(data), tags)
+	}
+	return errors.New("cant scan tags")
+}
+
+// Has check if tags have tag
+func (tags Tags) Has(tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
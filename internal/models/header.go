@@ -0,0 +1,135 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strings"
+)
+
+// HeaderField - one header line
+type HeaderField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Header - header lines in their original order and casing,
+// repeated headers like Set-Cookie are separate lines.
+// It is stored in database as json array
+//easyjson:json
+type Header []HeaderField
+
+// HeaderFromHTTP make Header from http.Header. Names are sorted,
+// because http.Header does not know the original order
+func HeaderFromHTTP(header http.Header) Header {
+	return OrderHeader(header, nil)
+}
+
+// OrderHeader make Header from http.Header keeping names, casing and
+// positions of original lines. Values are taken from header, so
+// changed, removed and added headers are taken into account. Names
+// missing in original are put in the end in sorted order
+func OrderHeader(header http.Header, original Header) Header {
+	var (
+		ordered = make(Header, 0, len(original))
+		used    = make(map[string]int)
+	)
+	for _, field := range original {
+		key := textproto.CanonicalMIMEHeaderKey(field.Name)
+		values := header[key]
+		if used[key] < len(values) {
+			ordered = append(ordered, HeaderField{field.Name, values[used[key]]})
+			used[key]++
+		}
+	}
+
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range header[k][used[k]:] {
+			ordered = append(ordered, HeaderField{k, v})
+		}
+	}
+	return ordered
+}
+
+// HTTP convert header to http.Header
+func (header Header) HTTP() http.Header {
+	var converted = make(http.Header, len(header))
+	for _, field := range header {
+		converted.Add(field.Name, field.Value)
+	}
+	return converted
+}
+
+// Get return value of the first line with name, case insensitive
+func (header Header) Get(name string) string {
+	for _, field := range header {
+		if strings.EqualFold(field.Name, name) {
+			return field.Value
+		}
+	}
+	return ""
+}
+
+// Del return header without lines with name, case insensitive
+func (header Header) Del(name string) Header {
+	var left = make(Header, 0, len(header))
+	for _, field := range header {
+		if !strings.EqualFold(field.Name, name) {
+			left = append(left, field)
+		}
+	}
+	return left
+}
+
+// Set return header where the first line with name has value and
+// other lines with name are removed. If there is no such line,
+// it is added to the end
+func (header Header) Set(name, value string) Header {
+	var (
+		changed = make(Header, 0, len(header)+1)
+		found   bool
+	)
+	for _, field := range header {
+		if !strings.EqualFold(field.Name, name) {
+			changed = append(changed, field)
+		} else if !found {
+			changed = append(changed, HeaderField{field.Name, value})
+			found = true
+		}
+	}
+	if !found {
+		changed = append(changed, HeaderField{name, value})
+	}
+	return changed
+}
+
+// Value put header to database as json
+func (header Header) Value() (driver.Value, error) {
+	if header == nil {
+		header = Header{}
+	}
+	b, err := json.Marshal(header)
+	return string(b), err
+}
+
+// Scan get header from database json
+func (header *Header) Scan(src interface{}) error {
+	switch data := src.(type) {
+	case nil:
+		*header = Header{}
+		return nil
+	case []byte:
+		return json.Unmarshal(data, header)
+	case string:
+		return json.Unmarshal([]byte(data), header)
+	}
+	return errors.New("cant scan header")
+}
@@ -0,0 +1,30 @@
+package models
+
+// ImportResult - what happened to entries of imported file
+//easyjson:json
+type ImportResult struct {
+	Imported int            `json:"imported"`
+	Failed   int            `json:"failed"`
+	Entries  []ImportStatus `json:"entries"`
+}
+
+// ImportStatus - result of import of entry with Index and Name, if
+// entries have names. ID - id of the saved request, Error - why
+// the entry was not saved
+type ImportStatus struct {
+	Index int    `json:"index"`
+	Name  string `json:"name,omitempty"`
+	ID    int    `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Add record result of import of entry
+func (result *ImportResult) Add(status ImportStatus, err error) {
+	if err != nil {
+		status.Error = err.Error()
+		result.Failed++
+	} else {
+		result.Imported++
+	}
+	result.Entries = append(result.Entries, status)
+}
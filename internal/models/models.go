@@ -1,6 +1,12 @@
 package models
 
 import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -8,71 +14,267 @@ import (
 // RequestDB wrapper for placing and retrieving http.Request from database
 //easyjson:json
 type RequestDB struct {
-	ID           int               `json:"id" db:"id"`
-	Method       string            `json:"method" db:"method"`
-	Scheme       string            `json:"scheme" db:"scheme"`
-	RemoteAddr   string            `json:"address" db:"address"`
-	Body         string            `json:"body" db:"body"`
-	HeaderRaw    string            `json:"header" db:"header"`
-	Header       map[string]string `json:"-" db:"-"`
-	UserLogin    string            `json:"-" db:"userlogin"`
-	UserPassword string            `json:"-" db:"userpassword"`
-	Add          time.Time         `json:"add" db:"add"`
+	ID         int    `json:"id" db:"id"`
+	Method     string `json:"method" db:"method"`
+	Scheme     string `json:"scheme" db:"scheme"`
+	RemoteAddr string `json:"address" db:"address"`
+	Path       string `json:"path" db:"path"`
+	RawQuery   string `json:"query" db:"query"`
+	URL        string `json:"url" db:"url"`
+	Proto      string `json:"proto" db:"proto"`
+	Body       []byte `json:"body" db:"body"`
+	Header     Header `json:"header" db:"header"`
+	// Truncated - only the beginning of the body was saved
+	Truncated    bool   `json:"truncated" db:"truncated"`
+	UserLogin    string `json:"-" db:"userlogin"`
+	UserPassword string `json:"-" db:"userpassword"`
+	Tags         Tags   `json:"tags" db:"tags"`
+	// Search - text indexed for search, see SearchText
+	Search string `json:"-" db:"search"`
+	// Parent - id of the request this one was resent from
+	Parent *int `json:"parent,omitempty" db:"parent"`
+	// ProjectID - project, the request belongs to
+	ProjectID int         `json:"project" db:"project_id"`
+	Add       time.Time   `json:"add" db:"add"`
+	Response  *ResponseDB `json:"response,omitempty" db:"-"`
 }
 
-// RequestsDB - slice of requsts from database
+// RequestPatch - changes applied to the stored request before sending
+// it again. Empty fields keep the stored values
 //easyjson:json
-type RequestsDB struct {
-	Requests []RequestDB `json:"requests"`
+type RequestPatch struct {
+	Method        string            `json:"method"`
+	URL           string            `json:"url"`
+	AddHeaders    map[string]string `json:"add_headers"`
+	RemoveHeaders []string          `json:"remove_headers"`
+	Body          *string           `json:"body"`
+	// BodyBase64 - binary body, it is used instead of Body if set
+	BodyBase64 []byte     `json:"body_base64"`
+	BasicAuth  *BasicAuth `json:"basic_auth"`
+}
+
+// BasicAuth - credentials of basic authorization.
+// Empty Login means no authorization
+type BasicAuth struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
 }
 
-// SEPHEADERS - headers separator
-// Need for separitng headers in string
-const SEPHEADERS = "\r\n"
-
-// SEPHEADER - one header separator
-// Need for separitng key and value in header
-const SEPHEADER = " : "
-
-// MakeHeader create a header map based on the header row
-// call it after retrieving Request from database
-func (rdb *RequestDB) MakeHeader() {
-	rdb.Header = make(map[string]string)
-	if rdb.HeaderRaw == "" {
-		return
-	}
-	// slice of headers
-	var headers = strings.Split(rdb.HeaderRaw, SEPHEADERS)
-	for _, header := range headers {
-		// slice of key and value
-		var kv = strings.Split(header, SEPHEADER)
-		if len(kv) != 2 {
-			continue
+// Apply change rdb with patch
+func (patch *RequestPatch) Apply(rdb *RequestDB) error {
+	if patch.Method != "" {
+		rdb.Method = strings.ToUpper(patch.Method)
+	}
+	if patch.URL != "" {
+		target, err := url.Parse(patch.URL)
+		if err != nil {
+			return err
+		}
+		if (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			return errors.New("url must be absolute http or https url")
+		}
+		target.Fragment = ""
+		rdb.Scheme = target.Scheme
+		rdb.RemoteAddr = target.Host
+		rdb.Path = target.EscapedPath()
+		rdb.RawQuery = target.RawQuery
+		if target.User != nil {
+			rdb.UserLogin = target.User.Username()
+			rdb.UserPassword, _ = target.User.Password()
+			target.User = nil
 		}
-		if kv[0] != "User-Agent" {
-			rdb.Header[kv[0]] = kv[1]
+		rdb.URL = target.String()
+		if rdb.Header.Get("Host") != "" {
+			rdb.Header = rdb.Header.Set("Host", target.Host)
 		}
 	}
+	for _, name := range patch.RemoveHeaders {
+		rdb.Header = rdb.Header.Del(name)
+	}
+	names := make([]string, 0, len(patch.AddHeaders))
+	for name := range patch.AddHeaders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		rdb.Header = rdb.Header.Set(name, patch.AddHeaders[name])
+	}
+	if patch.Body != nil {
+		rdb.Body = []byte(*patch.Body)
+		rdb.Truncated = false
+	}
+	if patch.BodyBase64 != nil {
+		rdb.Body = patch.BodyBase64
+		rdb.Truncated = false
+	}
+	if patch.BasicAuth != nil {
+		rdb.UserLogin = patch.BasicAuth.Login
+		rdb.UserPassword = patch.BasicAuth.Password
+		rdb.Header = rdb.Header.Del("Authorization")
+	}
+	return nil
 }
 
-// MakeHeaderRAW create string with headers from map with headers
-// call it before placing Request to database
-func (rdb *RequestDB) MakeHeaderRAW() {
-	if len(rdb.Header) == 0 {
-		return
-	}
+// Raw make HTTP/1.x request, keeping the order and casing of header
+// lines. Host is added if it is missing, Content-Length is set to the
+// body and headers belonging to the connection with proxy are dropped
+func (rdb *RequestDB) Raw() []byte {
 	var (
-		counter int
-		headers string
+		raw    bytes.Buffer
+		proto  = rdb.Proto
+		header = rdb.Header.Del("Proxy-Connection").Del("Proxy-Authorization")
+		target = rdb.Path
 	)
-	for k, v := range rdb.Header {
-		if counter != 0 {
-			headers += SEPHEADERS
-		}
-		headers += k + SEPHEADER + v
-		counter++
+	if proto != "HTTP/1.0" {
+		proto = "HTTP/1.1"
+	}
+	if target == "" {
+		target = "/"
+	}
+	if rdb.RawQuery != "" {
+		target += "?" + rdb.RawQuery
+	}
+	if header.Get("Host") == "" {
+		header = append(Header{{"Host", rdb.RemoteAddr}}, header...)
+	}
+	if rdb.UserLogin != "" && rdb.UserPassword != "" && header.Get("Authorization") == "" {
+		auth := base64.StdEncoding.EncodeToString([]byte(rdb.UserLogin + ":" + rdb.UserPassword))
+		header = append(header, HeaderField{"Authorization", "Basic " + auth})
+	}
+	// the body is stored decoded from chunks
+	header = header.Del("Transfer-Encoding")
+	if len(rdb.Body) > 0 || header.Get("Content-Length") != "" {
+		header = header.Set("Content-Length", strconv.Itoa(len(rdb.Body)))
+	}
+
+	raw.WriteString(rdb.Method + " " + target + " " + proto + "\r\n")
+	for _, field := range header {
+		raw.WriteString(field.Name + ": " + field.Value + "\r\n")
+	}
+	raw.WriteString("\r\n")
+	raw.Write(rdb.Body)
+	return raw.Bytes()
+}
+
+// ResponseDB wrapper for placing and retrieving http.Response from database
+//easyjson:json
+type ResponseDB struct {
+	ID        int    `json:"id" db:"id"`
+	RequestID int    `json:"request" db:"request_id"`
+	Proto     string `json:"proto" db:"proto"`
+	Status    int    `json:"status" db:"status"`
+	Body      []byte `json:"body" db:"body"`
+	Header    Header `json:"header" db:"header"`
+	// Size - size of the whole body, even if it was truncated
+	Size      int64  `json:"size" db:"size"`
+	Truncated bool   `json:"truncated" db:"truncated"`
+	Search    string `json:"-" db:"search"`
+	// Duration - time from sending request to receiving the whole body, ms
+	Duration int64     `json:"duration" db:"duration"`
+	Add      time.Time `json:"add" db:"add"`
+}
+
+// TargetURL return the url the request was sent to
+func (rdb *RequestDB) TargetURL() string {
+	if rdb.URL != "" {
+		return rdb.URL
+	}
+	var target = rdb.Scheme + "://" + rdb.RemoteAddr + rdb.Path
+	if rdb.RawQuery != "" {
+		target += "?" + rdb.RawQuery
 	}
-	rdb.HeaderRaw = headers
+	return target
+}
+
+// WebSocketMessage - one message of the websocket connection,
+// that was opened by the request with RequestID
+//easyjson:json
+type WebSocketMessage struct {
+	ID        int       `json:"id" db:"id"`
+	RequestID int       `json:"request" db:"request_id"`
+	Direction string    `json:"direction" db:"direction"`
+	Opcode    int       `json:"opcode" db:"opcode"`
+	Payload   []byte    `json:"payload" db:"payload"`
+	Add       time.Time `json:"add" db:"add"`
+}
+
+// Directions of websocket messages
+const (
+	WebSocketFromClient = "client"
+	WebSocketFromServer = "server"
+)
+
+// WebSocketMessages - slice of websocket messages from database
+//easyjson:json
+type WebSocketMessages struct {
+	Messages []WebSocketMessage `json:"messages"`
+}
+
+// InterceptSettings - which requests proxy holds until operator
+// forwards or drops them. Empty Host, Method and Path match everything
+//easyjson:json
+type InterceptSettings struct {
+	// ProjectID - project, which traffic is held
+	ProjectID int  `json:"project" db:"project_id"`
+	Enabled   bool `json:"enabled" db:"enabled"`
+	// Responses - hold also responses to the matched requests
+	Responses bool   `json:"responses" db:"responses"`
+	Host      string `json:"host" db:"host"`
+	Method    string `json:"method" db:"method"`
+	Path      string `json:"path" db:"path"`
+}
+
+// Match check if request with method to host and path must be held
+func (settings *InterceptSettings) Match(method, host, path string) bool {
+	if settings == nil || !settings.Enabled {
+		return false
+	}
+	return matchScope(settings.Method, settings.Host, settings.Path, method, host, path)
+}
+
+// Kinds of intercepted messages
+const (
+	InterceptRequest  = "request"
+	InterceptResponse = "response"
+)
+
+// States of intercepted messages
+const (
+	InterceptPending = "pending"
+	InterceptForward = "forward"
+	InterceptDrop    = "drop"
+)
+
+// InterceptItem - request or response held by proxy. Raw is the
+// message as it came, Modified is the message edited by operator
+//easyjson:json
+type InterceptItem struct {
+	ID         int       `json:"id" db:"id"`
+	Kind       string    `json:"kind" db:"kind"`
+	Scheme     string    `json:"scheme" db:"scheme"`
+	RemoteAddr string    `json:"address" db:"address"`
+	Raw        string    `json:"raw" db:"raw"`
+	Modified   string    `json:"modified,omitempty" db:"modified"`
+	State      string    `json:"state" db:"state"`
+	ProjectID  int       `json:"project" db:"project_id"`
+	Add        time.Time `json:"add" db:"add"`
+}
+
+// InterceptItems - slice of held messages
+//easyjson:json
+type InterceptItems struct {
+	Items []InterceptItem `json:"items"`
+}
+
+// RequestsDB - slice of requsts from database
+//easyjson:json
+type RequestsDB struct {
+	Requests []RequestDB `json:"requests"`
+	// Next - cursor of the next page: before_id for newer first order,
+	// after_id otherwise. It is absent on the last page
+	Next *int `json:"next,omitempty"`
+	// Total - how many requests match the filter on all pages
+	Total int `json:"total"`
 }
 
 // JSONtype is interface to be sent by json
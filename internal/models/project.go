@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// DefaultProject - id of project, that exists from the start.
+// Requests without project belong to it
+const DefaultProject = 1
+
+// ProjectDB - project, that keeps traffic of one engagement apart
+// from others. Proxy records to the active project, archived
+// project can not be active
+//easyjson:json
+type ProjectDB struct {
+	ID       int       `json:"id" db:"id"`
+	Name     string    `json:"name" db:"name"`
+	Archived bool      `json:"archived" db:"archived"`
+	Active   bool      `json:"active" db:"active"`
+	Add      time.Time `json:"add" db:"add"`
+}
+
+// ProjectsDB - slice of projects
+//easyjson:json
+type ProjectsDB struct {
+	Projects []ProjectDB `json:"projects"`
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Targets of match and replace rules
+const (
+	RuleRequestHeader  = "request_header"
+	RuleRequestBody    = "request_body"
+	RuleRequestURL     = "request_url"
+	RuleResponseHeader = "response_header"
+	RuleResponseBody   = "response_body"
+	RuleResponseStatus = "response_status"
+)
+
+// RuleDB - match and replace rule applied to the traffic going through
+// proxy. Rule is applied only to requests matching Host, Method and
+// Path, empty ones match everything. For headers rule is applied to
+// every "Name: value" line: empty Match adds Replace as a new header,
+// header replaced with empty string is removed
+//easyjson:json
+type RuleDB struct {
+	ID      int    `json:"id" db:"id"`
+	Enabled bool   `json:"enabled" db:"enabled"`
+	Target  string `json:"target" db:"target"`
+	Host    string `json:"host" db:"host"`
+	Method  string `json:"method" db:"method"`
+	Path    string `json:"path" db:"path"`
+	Match   string `json:"match" db:"pattern"`
+	Replace string `json:"replace" db:"replacement"`
+	// Regex - Match is regular expression and Replace can use $1 groups
+	Regex   bool   `json:"regex" db:"regex"`
+	Comment string `json:"comment" db:"comment"`
+	// ProjectID - project, which traffic the rule changes
+	ProjectID int       `json:"project" db:"project_id"`
+	Add       time.Time `json:"add" db:"add"`
+
+	matcher *regexp.Regexp
+}
+
+// RulesDB - slice of rules from database
+//easyjson:json
+type RulesDB struct {
+	Rules []RuleDB `json:"rules"`
+}
+
+// Compile check the rule and prepare it for applying
+func (rule *RuleDB) Compile() error {
+	switch rule.Target {
+	case RuleRequestHeader, RuleRequestBody, RuleRequestURL,
+		RuleResponseHeader, RuleResponseBody, RuleResponseStatus:
+	default:
+		return errors.New("unknown rule target " + rule.Target)
+	}
+	if !rule.Regex {
+		rule.matcher = nil
+		return nil
+	}
+	matcher, err := regexp.Compile(rule.Match)
+	if err != nil {
+		return err
+	}
+	rule.matcher = matcher
+	return nil
+}
+
+// Scoped check if rule must be applied to request with method to host and path
+func (rule *RuleDB) Scoped(method, host, path string) bool {
+	return rule.Enabled && matchScope(rule.Method, rule.Host, rule.Path, method, host, path)
+}
+
+// IsRequest check if rule changes requests, not responses
+func (rule *RuleDB) IsRequest() bool {
+	return strings.HasPrefix(rule.Target, "request_")
+}
+
+// Apply replace every match of rule in s. Call Compile before it
+func (rule *RuleDB) Apply(s string) string {
+	if rule.matcher != nil {
+		return rule.matcher.ReplaceAllString(s, rule.Replace)
+	}
+	if rule.Match == "" {
+		return s
+	}
+	return strings.Replace(s, rule.Match, rule.Replace, -1)
+}
+
+// matchScope check method, host and path against filters: method
+// must be equal, host must contain the filter and path start with it
+func matchScope(methodFilter, hostFilter, pathFilter, method, host, path string) bool {
+	if methodFilter != "" && !strings.EqualFold(methodFilter, method) {
+		return false
+	}
+	if hostFilter != "" && !strings.Contains(strings.ToLower(host), strings.ToLower(hostFilter)) {
+		return false
+	}
+	return strings.HasPrefix(path, pathFilter)
+}
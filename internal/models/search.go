@@ -0,0 +1,168 @@
+package models
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxSearchText - how many bytes of one message are indexed for search
+	maxSearchText = 1 << 20
+	// snippetRadius - how many bytes around the match are shown in snippet
+	snippetRadius = 60
+)
+
+// parts of request and response, where search text is found
+const (
+	SearchRequestURL     = "request_url"
+	SearchRequestHeader  = "request_header"
+	SearchRequestBody    = "request_body"
+	SearchResponseHeader = "response_header"
+	SearchResponseBody   = "response_body"
+)
+
+// SearchMatch - found text with some text around it. Start and End
+// are byte offsets of the found text in Snippet
+//easyjson:json
+type SearchMatch struct {
+	Part    string `json:"part"`
+	Snippet string `json:"snippet"`
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+}
+
+// SearchResult - found request with its matches. Bodies of request
+// and response are not sent, they can be got with /history/{id}
+//easyjson:json
+type SearchResult struct {
+	Request RequestDB     `json:"request"`
+	Matches []SearchMatch `json:"matches"`
+}
+
+// SearchResults - page of found requests
+//easyjson:json
+type SearchResults struct {
+	Results []SearchResult `json:"results"`
+	Next    *int           `json:"next,omitempty"`
+	Total   int            `json:"total"`
+}
+
+// SearchText return the indexed text of request: url, header lines
+// and body, if it is text
+func (rdb *RequestDB) SearchText() string {
+	return searchText(rdb.TargetURL(), rdb.Header, rdb.Body)
+}
+
+// SearchText return the indexed text of response: header lines
+// and body, if it is text
+func (respDB *ResponseDB) SearchText() string {
+	return searchText("", respDB.Header, respDB.Body)
+}
+
+func searchText(url string, header Header, body []byte) string {
+	var text strings.Builder
+	if url != "" {
+		text.WriteString(url + "\n")
+	}
+	text.WriteString(headerText(header) + "\n")
+	text.WriteString(ReadableBody(header, body))
+
+	indexed := text.String()
+	if len(indexed) > maxSearchText {
+		end := maxSearchText
+		for end > 0 && !utf8.RuneStart(indexed[end]) {
+			end--
+		}
+		indexed = indexed[:end]
+	}
+	return indexed
+}
+
+func headerText(header Header) string {
+	lines := make([]string, 0, len(header))
+	for _, field := range header {
+		lines = append(lines, field.Name+": "+field.Value)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ReadableBody return body decoded from Content-Encoding, if it is
+// utf-8 text, otherwise empty string
+func ReadableBody(header Header, body []byte) string {
+	decoded, err := DecodeBody(body, header.Get("Content-Encoding"))
+	if err == nil || len(decoded) > 0 {
+		body = decoded
+	}
+	if !utf8.Valid(body) {
+		return ""
+	}
+	// postgres text can not have zero bytes
+	return strings.Replace(string(body), "\x00", "", -1)
+}
+
+// Matches find text in the request and its response, case insensitive.
+// The first match in every part is returned
+func (rdb *RequestDB) Matches(text string) []SearchMatch {
+	var parts = []struct {
+		name, text string
+	}{
+		{SearchRequestURL, rdb.TargetURL()},
+		{SearchRequestHeader, headerText(rdb.Header)},
+		{SearchRequestBody, ReadableBody(rdb.Header, rdb.Body)},
+	}
+	if rdb.Response != nil {
+		parts = append(parts, []struct {
+			name, text string
+		}{
+			{SearchResponseHeader, headerText(rdb.Response.Header)},
+			{SearchResponseBody, ReadableBody(rdb.Response.Header, rdb.Response.Body)},
+		}...)
+	}
+
+	matches := make([]SearchMatch, 0)
+	for _, part := range parts {
+		if match, ok := findSnippet(part.text, text); ok {
+			match.Part = part.name
+			matches = append(matches, match)
+		}
+	}
+	return matches
+}
+
+// findSnippet find text in s and cut snippet around it
+func findSnippet(s, text string) (SearchMatch, bool) {
+	var (
+		match SearchMatch
+		start = -1
+	)
+	// offsets of lowered string are valid only if lowering
+	// did not change its length
+	if lower := strings.ToLower(s); len(lower) == len(s) {
+		start = strings.Index(lower, strings.ToLower(text))
+	} else {
+		start = strings.Index(s, text)
+	}
+	if start < 0 || text == "" {
+		return match, false
+	}
+	end := start + len(text)
+
+	from, to := start-snippetRadius, end+snippetRadius
+	if from < 0 {
+		from = 0
+	}
+	if to > len(s) {
+		to = len(s)
+	}
+	for from > 0 && !utf8.RuneStart(s[from]) {
+		from--
+	}
+	for to < len(s) && !utf8.RuneStart(s[to]) {
+		to++
+	}
+
+	match.Snippet = s[from:to]
+	match.Start = start - from
+	match.End = end - from
+	return match, true
+}
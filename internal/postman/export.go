@@ -0,0 +1,215 @@
+package postman
+
+import (
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+const (
+	// truncatedDescription - description of request, which body
+	// was saved truncated
+	truncatedDescription = "body is truncated"
+	// binaryDescription - description of request, which body
+	// is binary. Collections can not keep such bodies
+	binaryDescription = "body is binary and was not exported"
+)
+
+// skippedHeaders - headers, that Postman sets itself
+var skippedHeaders = []string{
+	"Content-Length",
+	"Transfer-Encoding",
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Connection",
+	"Proxy-Authorization",
+}
+
+// languages - languages of raw body by content type
+var languages = map[string]string{
+	"application/json":       "json",
+	"application/xml":        "xml",
+	"text/xml":               "xml",
+	"text/html":              "html",
+	"application/javascript": "javascript",
+	"text/javascript":        "javascript",
+}
+
+// folder - folder of collection being built
+type folder struct {
+	name     string
+	children []*folder
+	folders  map[string]*folder
+	// request - request item, if it is not folder
+	request *Item
+}
+
+// Export make collection of requests. Requests are put to folders
+// of their hosts and then of directories of their paths
+func Export(name string, requests []models.RequestDB) *Collection {
+	var root = &folder{}
+	for i := range requests {
+		rdb := &requests[i]
+		item := exportItem(rdb)
+		target, err := url.Parse(rdb.TargetURL())
+		if err != nil {
+			root.add(item)
+			continue
+		}
+
+		current := root.folder(target.Host)
+		segments := strings.Split(strings.Trim(target.EscapedPath(), "/"), "/")
+		for _, segment := range segments[:len(segments)-1] {
+			current = current.folder(segment)
+		}
+		current.add(item)
+	}
+	return &Collection{
+		Info: Info{Name: name, Schema: Schema},
+		Item: root.items(),
+	}
+}
+
+// folder return subfolder with name, creating it if needed
+func (f *folder) folder(name string) *folder {
+	if sub, ok := f.folders[name]; ok {
+		return sub
+	}
+	if f.folders == nil {
+		f.folders = make(map[string]*folder)
+	}
+	sub := &folder{name: name}
+	f.folders[name] = sub
+	f.children = append(f.children, sub)
+	return sub
+}
+
+func (f *folder) add(item *Item) {
+	f.children = append(f.children, &folder{request: item})
+}
+
+func (f *folder) items() []Item {
+	var items = make([]Item, 0, len(f.children))
+	for _, child := range f.children {
+		if child.request != nil {
+			items = append(items, *child.request)
+		} else {
+			items = append(items, Item{Name: child.name, Item: child.items()})
+		}
+	}
+	return items
+}
+
+func exportItem(rdb *models.RequestDB) *Item {
+	var request = &Request{
+		Method: rdb.Method,
+		Header: make([]Header, 0, len(rdb.Header)),
+		URL:    exportURL(rdb.TargetURL()),
+	}
+	header := rdb.Header
+	for _, name := range skippedHeaders {
+		header = header.Del(name)
+	}
+	if target, err := url.Parse(request.URL.Raw); err == nil && strings.EqualFold(header.Get("Host"), target.Host) {
+		header = header.Del("Host")
+	}
+	for _, field := range header {
+		request.Header = append(request.Header, Header{Key: field.Name, Value: field.Value})
+	}
+	if rdb.UserLogin != "" {
+		request.Auth = &Auth{Type: AuthBasic, Basic: []AuthParam{
+			{Key: "username", Value: rdb.UserLogin, Type: "string"},
+			{Key: "password", Value: rdb.UserPassword, Type: "string"},
+		}}
+	}
+
+	if len(rdb.Body) > 0 {
+		if utf8.Valid(rdb.Body) {
+			request.Body = exportBody(rdb.Body, rdb.Header.Get("Content-Type"))
+		} else {
+			request.Description = binaryDescription
+		}
+	}
+	if rdb.Truncated {
+		request.Description = truncatedDescription
+	}
+
+	name := rdb.Method + " " + rdb.Path
+	if rdb.RawQuery != "" {
+		name += "?" + rdb.RawQuery
+	}
+	return &Item{Name: name, Request: request}
+}
+
+// exportURL split url to parts. Parts are kept escaped
+// as in the raw url
+func exportURL(raw string) URL {
+	var u = URL{Raw: raw}
+	target, err := url.Parse(raw)
+	if err != nil {
+		return u
+	}
+	u.Protocol = target.Scheme
+	u.Host = strings.Split(target.Hostname(), ".")
+	u.Port = target.Port()
+	u.Path = strings.Split(strings.TrimPrefix(target.EscapedPath(), "/"), "/")
+	for _, pair := range strings.Split(target.RawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		var param = Param{Key: kv[0]}
+		if len(kv) == 2 {
+			param.Value = kv[1]
+		}
+		u.Query = append(u.Query, param)
+	}
+	return u
+}
+
+func exportBody(body []byte, contentType string) *Body {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	if mediaType == "application/x-www-form-urlencoded" {
+		if values, err := url.ParseQuery(string(body)); err == nil && len(values) > 0 {
+			return &Body{Mode: ModeURLEncoded, URLEncoded: exportForm(string(body))}
+		}
+	}
+
+	var raw = &Body{Mode: ModeRaw, Raw: string(body)}
+	language, ok := languages[mediaType]
+	if !ok && strings.HasSuffix(mediaType, "+json") {
+		language, ok = "json", true
+	}
+	if !ok {
+		language = "text"
+	}
+	raw.Options = &BodyOptions{}
+	raw.Options.Raw.Language = language
+	return raw
+}
+
+// exportForm split form to unescaped parameters keeping their order
+func exportForm(form string) []Param {
+	var params = make([]Param, 0)
+	for _, pair := range strings.Split(form, "&") {
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		var param = Param{Key: unescape(kv[0]), Type: "text"}
+		if len(kv) == 2 {
+			param.Value = unescape(kv[1])
+		}
+		params = append(params, param)
+	}
+	return params
+}
+
+func unescape(s string) string {
+	if unescaped, err := url.QueryUnescape(s); err == nil {
+		return unescaped
+	}
+	return s
+}
@@ -0,0 +1,274 @@
+package postman
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+// contentTypes - content types of raw body by language
+var contentTypes = map[string]string{
+	"json":       "application/json",
+	"xml":        "application/xml",
+	"html":       "text/html",
+	"javascript": "application/javascript",
+	"text":       "text/plain",
+}
+
+// variablePattern - use of variable in collection
+var variablePattern = regexp.MustCompile(`\{\{([^{}]+)\}\}`)
+
+// Entry - request of collection
+type Entry struct {
+	// Name - names of folders and request, separated by " / "
+	Name      string
+	request   *Request
+	auth      *Auth
+	variables map[string]string
+}
+
+// Entries return requests of collection from all folders. Requests
+// without authorization take it from their folders or collection
+func (collection *Collection) Entries() []Entry {
+	var variables = make(map[string]string, len(collection.Variable))
+	for _, variable := range collection.Variable {
+		if variable.Value != nil {
+			variables[variable.Key] = fmt.Sprint(variable.Value)
+		}
+	}
+	return entries(collection.Item, "", collection.Auth, variables)
+}
+
+func entries(items []Item, prefix string, auth *Auth, variables map[string]string) []Entry {
+	var found []Entry
+	for i := range items {
+		item := &items[i]
+		name := prefix + item.Name
+		if item.Request == nil {
+			folderAuth := auth
+			if item.Auth != nil {
+				folderAuth = item.Auth
+			}
+			found = append(found, entries(item.Item, name+" / ", folderAuth, variables)...)
+			continue
+		}
+		entry := Entry{Name: name, request: item.Request, auth: auth, variables: variables}
+		if item.Request.Auth != nil {
+			entry.auth = item.Request.Auth
+		}
+		found = append(found, entry)
+	}
+	return found
+}
+
+// resolve replace known variables in s
+func (entry *Entry) resolve(s string) string {
+	return variablePattern.ReplaceAllStringFunc(s, func(use string) string {
+		if value, ok := entry.variables[strings.TrimSpace(use[2:len(use)-2])]; ok {
+			return value
+		}
+		return use
+	})
+}
+
+// ToRequest make request of history from entry
+func (entry *Entry) ToRequest() (*models.RequestDB, error) {
+	raw := entry.resolve(entry.request.URL.Raw)
+	if raw == "" {
+		raw = entry.resolve(rawURL(&entry.request.URL))
+	}
+	if strings.Contains(raw, "{{") {
+		return nil, errors.New("url has unknown variables: " + raw)
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	target, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, errors.New("url must be http or https url")
+	}
+
+	var rdb = &models.RequestDB{
+		Method:    strings.ToUpper(entry.request.Method),
+		Proto:     "HTTP/1.1",
+		Truncated: entry.request.Description == truncatedDescription,
+	}
+	if rdb.Method == "" {
+		rdb.Method = "GET"
+	}
+	for _, header := range entry.request.Header {
+		if !header.Disabled {
+			rdb.Header = append(rdb.Header, models.HeaderField{Name: entry.resolve(header.Key), Value: entry.resolve(header.Value)})
+		}
+	}
+	if err = entry.applyAuth(rdb, target); err != nil {
+		return nil, err
+	}
+	if err = entry.applyBody(rdb); err != nil {
+		return nil, err
+	}
+
+	if target.User != nil && rdb.UserLogin == "" {
+		rdb.UserLogin = target.User.Username()
+		rdb.UserPassword, _ = target.User.Password()
+	}
+	target.User = nil
+	target.Fragment = ""
+	rdb.Scheme = target.Scheme
+	rdb.RemoteAddr = target.Host
+	rdb.Path = target.EscapedPath()
+	rdb.RawQuery = target.RawQuery
+	rdb.URL = target.String()
+	if rdb.Header.Get("Host") == "" {
+		rdb.Header = append(models.Header{{Name: "Host", Value: target.Host}}, rdb.Header...)
+	}
+	return rdb, nil
+}
+
+// rawURL make url from its parts, if collection has no raw url
+func rawURL(u *URL) string {
+	var raw = strings.Join(u.Host, ".")
+	if u.Protocol != "" {
+		raw = u.Protocol + "://" + raw
+	}
+	if u.Port != "" {
+		raw += ":" + u.Port
+	}
+	if len(u.Path) > 0 {
+		raw += "/" + strings.Join(u.Path, "/")
+	}
+	var query []string
+	for _, param := range u.Query {
+		if !param.Disabled {
+			query = append(query, param.Key+"="+param.Value)
+		}
+	}
+	if len(query) > 0 {
+		raw += "?" + strings.Join(query, "&")
+	}
+	return raw
+}
+
+func (entry *Entry) applyAuth(rdb *models.RequestDB, target *url.URL) error {
+	if entry.auth == nil {
+		return nil
+	}
+	switch entry.auth.Type {
+	case "", AuthNone:
+	case AuthBasic:
+		rdb.UserLogin = entry.authParam(entry.auth.Basic, "username")
+		rdb.UserPassword = entry.authParam(entry.auth.Basic, "password")
+	case AuthBearer:
+		rdb.Header = rdb.Header.Set("Authorization", "Bearer "+entry.authParam(entry.auth.Bearer, "token"))
+	case AuthAPIKey:
+		key := entry.authParam(entry.auth.APIKey, "key")
+		value := entry.authParam(entry.auth.APIKey, "value")
+		if entry.authParam(entry.auth.APIKey, "in") == "query" {
+			query := url.QueryEscape(key) + "=" + url.QueryEscape(value)
+			if target.RawQuery != "" {
+				query = target.RawQuery + "&" + query
+			}
+			target.RawQuery = query
+		} else {
+			rdb.Header = rdb.Header.Set(key, value)
+		}
+	default:
+		return errors.New("unsupported auth type " + entry.auth.Type)
+	}
+	return nil
+}
+
+func (entry *Entry) authParam(params []AuthParam, key string) string {
+	for _, param := range params {
+		if param.Key == key && param.Value != nil {
+			return entry.resolve(fmt.Sprint(param.Value))
+		}
+	}
+	return ""
+}
+
+func (entry *Entry) applyBody(rdb *models.RequestDB) error {
+	body := entry.request.Body
+	if body == nil || body.Disabled {
+		return nil
+	}
+
+	var contentType string
+	switch body.Mode {
+	case "":
+		return nil
+	case ModeRaw:
+		rdb.Body = []byte(entry.resolve(body.Raw))
+		contentType = "text/plain"
+		if body.Options != nil {
+			if known, ok := contentTypes[body.Options.Raw.Language]; ok {
+				contentType = known
+			}
+		}
+	case ModeURLEncoded:
+		var form []string
+		for _, param := range body.URLEncoded {
+			if !param.Disabled {
+				form = append(form, url.QueryEscape(entry.resolve(param.Key))+"="+url.QueryEscape(entry.resolve(param.Value)))
+			}
+		}
+		rdb.Body = []byte(strings.Join(form, "&"))
+		contentType = "application/x-www-form-urlencoded"
+	case ModeFormData:
+		var (
+			buffer bytes.Buffer
+			writer = multipart.NewWriter(&buffer)
+		)
+		for _, param := range body.FormData {
+			if param.Disabled {
+				continue
+			}
+			if param.Type == "file" {
+				return errors.New("files are not supported")
+			}
+			if err := writer.WriteField(entry.resolve(param.Key), entry.resolve(param.Value)); err != nil {
+				return err
+			}
+		}
+		if err := writer.Close(); err != nil {
+			return err
+		}
+		rdb.Body = buffer.Bytes()
+		// boundary must be the same as in body
+		rdb.Header = rdb.Header.Set("Content-Type", writer.FormDataContentType())
+	case ModeGraphQL:
+		var query = map[string]interface{}{}
+		if body.GraphQL != nil {
+			query["query"] = entry.resolve(body.GraphQL.Query)
+			var variables interface{}
+			if json.Unmarshal([]byte(entry.resolve(body.GraphQL.Variables)), &variables) == nil {
+				query["variables"] = variables
+			}
+		}
+		encoded, err := json.Marshal(query)
+		if err != nil {
+			return err
+		}
+		rdb.Body = encoded
+		contentType = "application/json"
+	case ModeFile:
+		return errors.New("files are not supported")
+	default:
+		return errors.New("unsupported body mode " + body.Mode)
+	}
+
+	if contentType != "" && len(rdb.Body) > 0 && rdb.Header.Get("Content-Type") == "" {
+		rdb.Header = append(rdb.Header, models.HeaderField{Name: "Content-Type", Value: contentType})
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+// Package postman converts history to Postman collections v2.1 and back.
+// See https://schema.postman.com/collection/json/v2.1.0/draft-07/docs/index.html
+package postman
+
+import "encoding/json"
+
+// Schema - schema of collections v2.1
+const Schema = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+// Collection - root of the collection
+//easyjson:json
+type Collection struct {
+	Info     Info       `json:"info"`
+	Item     []Item     `json:"item"`
+	Auth     *Auth      `json:"auth,omitempty"`
+	Variable []Variable `json:"variable,omitempty"`
+}
+
+// Info - information about the collection
+type Info struct {
+	PostmanID string `json:"_postman_id,omitempty"`
+	Name      string `json:"name"`
+	Schema    string `json:"schema"`
+}
+
+// Item - request or, if Request is nil, folder with items
+type Item struct {
+	Name    string   `json:"name"`
+	Item    []Item   `json:"item,omitempty"`
+	Request *Request `json:"request,omitempty"`
+	// Auth - authorization of requests of the folder
+	Auth *Auth `json:"auth,omitempty"`
+}
+
+// Request - request of the collection
+type Request struct {
+	Method      string      `json:"method"`
+	Header      []Header    `json:"header"`
+	Body        *Body       `json:"body,omitempty"`
+	URL         URL         `json:"url"`
+	Auth        *Auth       `json:"auth,omitempty"`
+	Description Description `json:"description,omitempty"`
+}
+
+// UnmarshalJSON read request, that can be also just url
+func (request *Request) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		*request = Request{Method: "GET", URL: URL{Raw: raw}}
+		return nil
+	}
+	type plain Request
+	return json.Unmarshal(data, (*plain)(request))
+}
+
+// URL - url of request. Postman uses Raw, other fields are its parts
+type URL struct {
+	Raw      string   `json:"raw"`
+	Protocol string   `json:"protocol,omitempty"`
+	Host     []string `json:"host,omitempty"`
+	Port     string   `json:"port,omitempty"`
+	Path     []string `json:"path,omitempty"`
+	Query    []Param  `json:"query,omitempty"`
+}
+
+// UnmarshalJSON read url, that can be also string
+func (u *URL) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		*u = URL{Raw: raw}
+		return nil
+	}
+	type plain URL
+	return json.Unmarshal(data, (*plain)(u))
+}
+
+// Header - header field
+type Header struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+// Param - parameter of query or form. Type of form parameter
+// is text or file
+type Param struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Type     string `json:"type,omitempty"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+// modes of body
+const (
+	ModeRaw        = "raw"
+	ModeURLEncoded = "urlencoded"
+	ModeFormData   = "formdata"
+	ModeFile       = "file"
+	ModeGraphQL    = "graphql"
+)
+
+// Body - body of request in one of modes
+type Body struct {
+	Mode       string       `json:"mode"`
+	Raw        string       `json:"raw,omitempty"`
+	URLEncoded []Param      `json:"urlencoded,omitempty"`
+	FormData   []Param      `json:"formdata,omitempty"`
+	GraphQL    *GraphQL     `json:"graphql,omitempty"`
+	Options    *BodyOptions `json:"options,omitempty"`
+	Disabled   bool         `json:"disabled,omitempty"`
+}
+
+// GraphQL - graphql query. Variables are JSON text
+type GraphQL struct {
+	Query     string `json:"query"`
+	Variables string `json:"variables,omitempty"`
+}
+
+// BodyOptions - how raw body is shown
+type BodyOptions struct {
+	Raw struct {
+		Language string `json:"language"`
+	} `json:"raw"`
+}
+
+// types of authorization
+const (
+	AuthNone   = "noauth"
+	AuthBasic  = "basic"
+	AuthBearer = "bearer"
+	AuthAPIKey = "apikey"
+)
+
+// Auth - authorization. Parameters are in the field of its type
+type Auth struct {
+	Type   string      `json:"type"`
+	Basic  []AuthParam `json:"basic,omitempty"`
+	Bearer []AuthParam `json:"bearer,omitempty"`
+	APIKey []AuthParam `json:"apikey,omitempty"`
+}
+
+// AuthParam - parameter of authorization
+type AuthParam struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+	Type  string      `json:"type,omitempty"`
+}
+
+// Variable - variable of collection, used as {{key}}
+type Variable struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// Description - text, that can be also object with content
+type Description string
+
+// UnmarshalJSON read description as string or object
+func (description *Description) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*description = Description(text)
+		return nil
+	}
+	var object struct {
+		Content string `json:"content"`
+	}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return err
+	}
+	*description = Description(object.Content)
+	return nil
+}
@@ -0,0 +1,134 @@
+package postman
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+// TestRoundTrip export requests to collection, write it as json
+// and import requests back
+func TestRoundTrip(t *testing.T) {
+	requests := []models.RequestDB{
+		{
+			Method: "GET", URL: "https://mail.ru/api/v1/users?id=1&sort=name",
+			Path: "/api/v1/users", RawQuery: "id=1&sort=name",
+			Header: models.Header{{Name: "Host", Value: "mail.ru"}, {Name: "Accept", Value: "*/*"},
+				{Name: "Connection", Value: "close"}},
+		},
+		{
+			Method: "POST", URL: "https://mail.ru/api/v1/login", Path: "/api/v1/login",
+			Header: models.Header{{Name: "Host", Value: "mail.ru"},
+				{Name: "Content-Type", Value: "application/x-www-form-urlencoded"}},
+			Body:      []byte("user=a+b&password=%26"),
+			UserLogin: "admin", UserPassword: "pa:ss",
+		},
+		{
+			Method: "PUT", URL: "http://example.com:8080/items/1", Path: "/items/1",
+			Header: models.Header{{Name: "Host", Value: "example.com:8080"},
+				{Name: "Content-Type", Value: "application/json"}},
+			Body: []byte(`{"name": "it's\n"}`),
+		},
+		{
+			Method: "POST", URL: "http://example.com:8080/upload", Path: "/upload",
+			Header: models.Header{{Name: "Host", Value: "example.com:8080"},
+				{Name: "Content-Type", Value: "application/octet-stream"}},
+			Body: []byte{0, 0xff},
+		},
+		{
+			Method: "POST", URL: "http://other.com/", Path: "/",
+			Header: models.Header{{Name: "Host", Value: "other.com"}, {Name: "Content-Type", Value: "text/plain"}},
+			Body:   []byte("part"), Truncated: true,
+		},
+	}
+	// wants - requests after import in order of folders
+	wants := []struct {
+		name      string
+		request   models.RequestDB
+		truncated bool
+	}{
+		{"mail.ru / api / v1 / GET /api/v1/users?id=1&sort=name", requests[0], false},
+		{"mail.ru / api / v1 / POST /api/v1/login", requests[1], false},
+		{"example.com:8080 / items / PUT /items/1", requests[2], false},
+		{"example.com:8080 / POST /upload", models.RequestDB{
+			// binary body is not exported
+			Method: "POST", URL: "http://example.com:8080/upload",
+			Header: models.Header{{Name: "Host", Value: "example.com:8080"},
+				{Name: "Content-Type", Value: "application/octet-stream"}},
+		}, false},
+		{"other.com / POST /", requests[4], true},
+	}
+	// Connection is set by Postman itself
+	wants[0].request.Header = wants[0].request.Header[:2]
+
+	data, err := json.Marshal(Export("mail.ru", requests))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var collection Collection
+	if err = json.Unmarshal(data, &collection); err != nil {
+		t.Fatal(err)
+	}
+	if collection.Info.Name != "mail.ru" || collection.Info.Schema != Schema {
+		t.Errorf("collection has info %+v", collection.Info)
+	}
+	entries := collection.Entries()
+	if len(entries) != len(wants) {
+		t.Fatalf("collection has %d requests, want %d", len(entries), len(wants))
+	}
+	for i, want := range wants {
+		t.Run(want.name, func(t *testing.T) {
+			if entries[i].Name != want.name {
+				t.Errorf("request is named %q", entries[i].Name)
+			}
+			rdb, err := entries[i].ToRequest()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rdb.Method != want.request.Method || rdb.URL != want.request.URL ||
+				!bytes.Equal(rdb.Body, want.request.Body) || rdb.Truncated != want.truncated {
+				t.Errorf("imported request\n%+v\nwant\n%+v", rdb, want.request)
+			}
+			if !reflect.DeepEqual(rdb.Header, want.request.Header) {
+				t.Errorf("header %v, want %v", rdb.Header, want.request.Header)
+			}
+			if rdb.UserLogin != want.request.UserLogin || rdb.UserPassword != want.request.UserPassword {
+				t.Errorf("user %q:%q, want %q:%q", rdb.UserLogin, rdb.UserPassword,
+					want.request.UserLogin, want.request.UserPassword)
+			}
+		})
+	}
+}
+
+func TestImportVariables(t *testing.T) {
+	var collection Collection
+	err := json.Unmarshal([]byte(`{
+		"info": {"name": "c", "schema": "`+Schema+`"},
+		"variable": [{"key": "host", "value": "mail.ru"}, {"key": "id", "value": 5}],
+		"auth": {"type": "bearer", "bearer": [{"key": "token", "value": "t{{id}}"}]},
+		"item": [
+			{"name": "f", "item": [{"name": "r", "request": {"method": "get", "url": "https://{{host}}/a/{{id}}"}}]},
+			{"name": "unknown", "request": "https://{{other}}/"}
+		]
+	}`), &collection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := collection.Entries()
+	if len(entries) != 2 || entries[0].Name != "f / r" {
+		t.Fatalf("collection has entries %+v", entries)
+	}
+	rdb, err := entries[0].ToRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rdb.Method != "GET" || rdb.URL != "https://mail.ru/a/5" || rdb.Header.Get("Authorization") != "Bearer t5" {
+		t.Errorf("imported request %+v", rdb)
+	}
+	if _, err = entries[1].ToRequest(); err == nil {
+		t.Error("request with unknown variable is imported")
+	}
+}
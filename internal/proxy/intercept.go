@@ -0,0 +1,251 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+const (
+	// settingsTTL - how often proxy reloads settings changed by repeater
+	settingsTTL = time.Second
+	// interceptPoll - how often proxy checks if the held message is decided
+	interceptPoll = 300 * time.Millisecond
+)
+
+var (
+	// errDropped - operator dropped the held message
+	errDropped = errors.New("dropped by intercept")
+	// errNotHeld - message could not be put to the queue or checked
+	// there, so it is not sent without the decision of operator
+	errNotHeld = errors.New("cant hold message for intercept")
+)
+
+// watch reload settings and rules, that can be changed through repeater
+func (proxy *Proxy) watch() {
+	for {
+		time.Sleep(settingsTTL)
+		proxy.reload()
+	}
+}
+
+// reload load the active project, its rules and intercept settings
+func (proxy *Proxy) reload() {
+	project := proxy.loadProject()
+	proxy.loadRules(project)
+	settings, err := proxy.db.GetInterceptSettings(project)
+	if err != nil {
+		log.Printf("Error, cant load intercept settings: %v", err)
+		return
+	}
+	proxy.mutex.Lock()
+	proxy.intercept = settings
+	proxy.mutex.Unlock()
+}
+
+func (proxy *Proxy) interceptSettings() *models.InterceptSettings {
+	proxy.mutex.RLock()
+	defer proxy.mutex.RUnlock()
+	return proxy.intercept
+}
+
+// interceptRequest hold r until operator forwards or drops it.
+// It returns the request to send: r itself or the edited one
+func (proxy *Proxy) interceptRequest(w http.ResponseWriter, r *http.Request) (*http.Request, error) {
+	item, err := proxy.hold(w, r, &models.InterceptItem{
+		Kind:       models.InterceptRequest,
+		Scheme:     r.URL.Scheme,
+		RemoteAddr: r.URL.Host,
+		Raw:        dumpRequest(r, readBody(r)),
+	})
+	if err != nil {
+		return r, err
+	}
+	if item.Modified == "" {
+		return r, nil
+	}
+	modified, err := parseRawRequest(item.Modified, r)
+	if err != nil {
+		log.Printf("Error, cant parse edited request: %v", err)
+		return r, nil
+	}
+	return modified, nil
+}
+
+// interceptResponse hold resp to r until operator forwards or drops it.
+// body is the read body of resp. It returns the response to send
+func (proxy *Proxy) interceptResponse(w http.ResponseWriter, r *http.Request, resp *http.Response, body []byte) (*http.Response, error) {
+	item, err := proxy.hold(w, r, &models.InterceptItem{
+		Kind:       models.InterceptResponse,
+		Scheme:     r.URL.Scheme,
+		RemoteAddr: r.URL.Host,
+		Raw:        dumpResponse(resp, body),
+	})
+	if err != nil {
+		return resp, err
+	}
+	if item.Modified == "" {
+		return resp, nil
+	}
+	modified, err := parseRawResponse(item.Modified, r)
+	if err != nil {
+		log.Printf("Error, cant parse edited response: %v", err)
+		return resp, nil
+	}
+	return modified, nil
+}
+
+// hold put item to the queue and wait for operator. It returns the
+// decided item or error if item could not be held, was dropped or
+// the client went away. Timeouts of server do not limit the waiting,
+// the answer to r gets the whole WriteTimeout after it
+func (proxy *Proxy) hold(w http.ResponseWriter, r *http.Request, item *models.InterceptItem) (*models.InterceptItem, error) {
+	item.ProjectID = proxy.activeProject()
+	if err := proxy.db.CreateInterceptItem(item); err != nil {
+		log.Printf("Error, cant hold %s: %v", item.Kind, err)
+		return nil, errNotHeld
+	}
+	defer proxy.db.DeleteInterceptItem(int32(item.ID))
+
+	controller := http.NewResponseController(w)
+	setDeadlines(controller, time.Time{}, time.Time{})
+	defer func() {
+		var write time.Time
+		if proxy.server != nil && proxy.server.WriteTimeout > 0 {
+			write = time.Now().Add(proxy.server.WriteTimeout)
+		}
+		setDeadlines(controller, time.Time{}, write)
+	}()
+
+	ticker := time.NewTicker(interceptPoll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil, r.Context().Err()
+		case <-ticker.C:
+			current, err := proxy.db.GetInterceptItem(int32(item.ID))
+			if err != nil {
+				log.Printf("Error, cant check held %s: %v", item.Kind, err)
+				return nil, errNotHeld
+			}
+			switch current.State {
+			case models.InterceptPending:
+				continue
+			case models.InterceptDrop:
+				return nil, errDropped
+			default:
+				return current, nil
+			}
+		}
+	}
+}
+
+// setDeadlines set deadlines of reading the request and writing the
+// answer of controller. Zero time means no deadline
+func setDeadlines(controller *http.ResponseController, read, write time.Time) {
+	if err := controller.SetReadDeadline(read); err != nil {
+		log.Printf("Error, cant set read deadline: %v", err)
+	}
+	if err := controller.SetWriteDeadline(write); err != nil {
+		log.Printf("Error, cant set write deadline: %v", err)
+	}
+}
+
+// readBody read the whole body of r and replace it, so r still can be sent
+func readBody(r *http.Request) []byte {
+	if r.Body == nil {
+		return nil
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("Error reading body: %v", err)
+	}
+	r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body
+}
+
+func dumpRequest(r *http.Request, body []byte) string {
+	var raw bytes.Buffer
+	fmt.Fprintf(&raw, "%s %s %s\r\n", r.Method, r.URL.RequestURI(), r.Proto)
+	host := r.Host
+	if host == "" {
+		host = r.URL.Host
+	}
+	fmt.Fprintf(&raw, "Host: %s\r\n", host)
+	r.Header.WriteSubset(&raw, map[string]bool{"Host": true})
+	raw.WriteString("\r\n")
+	raw.Write(body)
+	return raw.String()
+}
+
+func dumpResponse(resp *http.Response, body []byte) string {
+	var raw bytes.Buffer
+	fmt.Fprintf(&raw, "%s %s\r\n", resp.Proto, resp.Status)
+	resp.Header.Write(&raw)
+	raw.WriteString("\r\n")
+	raw.Write(body)
+	return raw.String()
+}
+
+// parseRawRequest parse request edited by operator. The target of
+// original is kept and Content-Length is set to the edited body
+func parseRawRequest(raw string, original *http.Request) (*http.Request, error) {
+	head, body := splitRaw(raw)
+	r, err := http.ReadRequest(bufio.NewReader(strings.NewReader(head)))
+	if err != nil {
+		return nil, err
+	}
+	r.URL.Scheme = original.URL.Scheme
+	r.URL.Host = original.URL.Host
+	r.RemoteAddr = original.RemoteAddr
+	r.TransferEncoding = nil
+	r.Header.Del("Transfer-Encoding")
+	r.ContentLength = int64(len(body))
+	r.Body = ioutil.NopCloser(strings.NewReader(body))
+	if len(body) > 0 || r.Header.Get("Content-Length") != "" {
+		r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	}
+	return r.WithContext(original.Context()), nil
+}
+
+// parseRawResponse parse response to r edited by operator.
+// Content-Length is set to the edited body
+func parseRawResponse(raw string, r *http.Request) (*http.Response, error) {
+	head, body := splitRaw(raw)
+	resp, err := http.ReadResponse(bufio.NewReader(strings.NewReader(head)), r)
+	if err != nil {
+		return nil, err
+	}
+	resp.TransferEncoding = nil
+	resp.Header.Del("Transfer-Encoding")
+	resp.ContentLength = int64(len(body))
+	resp.Body = ioutil.NopCloser(strings.NewReader(body))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	return resp, nil
+}
+
+// splitRaw split raw http message to head, ending with an empty
+// line, and body. Lines of head can be separated by "\n" only
+func splitRaw(raw string) (head, body string) {
+	var end, separator = -1, ""
+	for _, sep := range []string{"\r\n\r\n", "\n\n"} {
+		if i := strings.Index(raw, sep); i >= 0 && (end < 0 || i < end) {
+			end, separator = i, sep
+		}
+	}
+	if end < 0 {
+		return strings.TrimRight(raw, "\r\n") + "\r\n\r\n", ""
+	}
+	return raw[:end] + "\r\n\r\n", raw[end+len(separator):]
+}
@@ -0,0 +1,40 @@
+package proxy
+
+import (
+	"log"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+// loadProject reload the active project, that can be switched
+// through repeater, and return its id. The loaded one is kept
+// if the active project can not be read
+func (proxy *Proxy) loadProject() int {
+	project, err := proxy.db.GetActiveProject()
+	proxy.mutex.Lock()
+	defer proxy.mutex.Unlock()
+	if err != nil {
+		log.Printf("Error, cant load active project: %v", err)
+	} else {
+		proxy.project = project.ID
+	}
+	if proxy.project == 0 {
+		proxy.project = models.DefaultProject
+	}
+	return proxy.project
+}
+
+func (proxy *Proxy) activeProject() int {
+	proxy.mutex.RLock()
+	defer proxy.mutex.RUnlock()
+	return proxy.project
+}
+
+// createRequest save rdb to its project or to the active one,
+// if project is not set
+func (proxy *Proxy) createRequest(rdb *models.RequestDB) error {
+	if rdb.ProjectID == 0 {
+		rdb.ProjectID = proxy.activeProject()
+	}
+	return proxy.db.CreateRequest(rdb)
+}
@@ -1,7 +1,6 @@
 package proxy
 
 import (
-	"bufio"
 	"bytes"
 	"crypto/tls"
 	"errors"
@@ -11,7 +10,9 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/SmartPhoneJava/SecurityProxyServer/internal/database"
@@ -19,10 +20,20 @@ import (
 	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
 )
 
+// maxBodySize - how many bytes of one body are saved. Longer bodies
+// are forwarded fully but saved truncated
+const maxBodySize = 10 << 20
+
 type Proxy struct {
 	server *http.Server
 	client *http.Client
-	db     *database.DB
+	db     database.Store
+
+	mutex     sync.RWMutex
+	intercept *models.InterceptSettings
+	rules     []models.RuleDB
+	// project - id of the active project, traffic is saved to it
+	project int
 }
 
 func Init() (*Proxy, error) {
@@ -54,20 +65,15 @@ func Init() (*Proxy, error) {
 
 	// database config
 
-	proxy.db, err = database.Init(database.Settings{
-		User:     "classic",
-		Password: "nopassword",
-		Addr:     "proxy-db",
-		Port:     ":5432",
-		Db:       "proxybase",
-		MaxOpen:  20,
-		MaxIdle:  20,
-		TTL:      time.Hour,
-	})
+	proxy.db, err = database.Open(database.ConfigFromEnv())
 	if err != nil {
 		log.Println("ERROR with database:", err.Error())
 		return nil, err
 	}
+	// repeater sends requests through proxy without running it,
+	// so the active project is loaded and watched from the start
+	proxy.reload()
+	go proxy.watch()
 
 	//client config
 	var (
@@ -81,10 +87,18 @@ func Init() (*Proxy, error) {
 			Timeout: dialTimeout,
 		}).Dial,
 		TLSHandshakeTimeout: TLSHandshakeTimeout,
+		// custom Dial turns h2 off unless it is forced
+		ForceAttemptHTTP2: true,
+		// pass bodies to the client as they are
+		DisableCompression: true,
 	}
 	proxy.client = &http.Client{
 		Timeout:   clientTimeout,
 		Transport: netTransport,
+		// redirects are answers to the client, not to the proxy
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
 	}
 
 	proxy.server = &http.Server{
@@ -94,8 +108,8 @@ func Init() (*Proxy, error) {
 		IdleTimeout:    idleTimeout,
 		Handler:        http.HandlerFunc(proxy.ProxyHandler()),
 		MaxHeaderBytes: maxHeaderBytes,
-		TLSNextProto:   make(map[string]func(*http.Server, *tls.Conn, http.Handler)),
 		TLSConfig:      config,
+		ConnContext:    connContext,
 	}
 
 	return proxy, nil
@@ -103,7 +117,12 @@ func Init() (*Proxy, error) {
 
 func (proxy *Proxy) Run() {
 	fmt.Println("Proxy launched on ", proxy.server.Addr)
-	proxy.server.ListenAndServe()
+	listener, err := net.Listen("tcp", proxy.server.Addr)
+	if err != nil {
+		log.Println("ERROR with proxy:", err.Error())
+		return
+	}
+	proxy.server.Serve(recordListener{listener})
 }
 
 func (proxy *Proxy) Close() {
@@ -114,26 +133,23 @@ func (proxy *Proxy) Certificate() *tls.Certificate {
 	return &proxy.server.TLSConfig.Certificates[0]
 }
 
-func (proxy *Proxy) DB() *database.DB {
+func (proxy *Proxy) DB() database.Store {
 	return proxy.db
 }
 
 func (proxy *Proxy) HandleTunneling(w http.ResponseWriter, r *http.Request) {
-
-	cer, err := mitm.GenerateCert(proxy.Certificate(), r.Host)
+	hostname := r.URL.Hostname()
 	config := &tls.Config{
-		Certificates: []tls.Certificate{*cer},
+		// http.Server serving the tunnel speaks h2 when the client agrees
+		NextProtos: []string{"h2", "http/1.1"},
 		GetCertificate: func(info *tls.ClientHelloInfo) (certificate *tls.Certificate, e error) {
-			return mitm.GenerateCert(proxy.Certificate(), info.ServerName)
+			if info.ServerName != "" {
+				return mitm.GenerateCert(proxy.Certificate(), info.ServerName)
+			}
+			return mitm.GenerateCert(proxy.Certificate(), hostname)
 		},
 	}
 
-	destConn, err := tls.Dial("tcp", r.Host, config)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusServiceUnavailable)
-		return
-	}
-
 	hijacker, ok := w.(http.Hijacker)
 	if !ok {
 		http.Error(w, "Hijacking not supported", http.StatusInternalServerError)
@@ -143,89 +159,190 @@ func (proxy *Proxy) HandleTunneling(w http.ResponseWriter, r *http.Request) {
 	clientConn, _, err := hijacker.Hijack()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
 	}
 	_, err = clientConn.Write([]byte("HTTP/1.1 200 OK\r\n\r\n"))
+	if err != nil {
+		clientConn.Close()
+		return
+	}
 
+	println(r.RequestURI, " - request catched")
+	if recorded, ok := clientConn.(*recordConn); ok {
+		clientConn = recorded.Conn
+	}
 	tlsConn := tls.Server(clientConn, config)
-	err = tlsConn.Handshake()
+	tlsConn.SetDeadline(time.Now().Add(proxy.server.ReadTimeout))
+	if err = tlsConn.Handshake(); err != nil {
+		log.Printf("%s - tls handshake failed: %v", r.URL.Host, err)
+		tlsConn.Close()
+		return
+	}
+	tlsConn.SetDeadline(time.Time{})
 
-	println(r.RequestURI, " - request catched")
-	go proxy.transfer(destConn, tlsConn, true, r.URL.Host)
-	go proxy.transfer(tlsConn, destConn, false, r.URL.Host)
+	// header lines of HTTP/1.x requests are recorded to keep their
+	// order, h2 needs *tls.Conn to be served
+	var conn net.Conn = tlsConn
+	if tlsConn.ConnectionState().NegotiatedProtocol != "h2" {
+		conn = &recordConn{Conn: tlsConn}
+	}
+	listener := newConnListener(conn)
+	tunnel := &http.Server{
+		ReadTimeout:    proxy.server.ReadTimeout,
+		WriteTimeout:   proxy.server.WriteTimeout,
+		IdleTimeout:    proxy.server.IdleTimeout,
+		MaxHeaderBytes: proxy.server.MaxHeaderBytes,
+		Handler:        proxy.tunnelHandler(r.URL.Host),
+		ConnState:      listener.connState,
+		ConnContext:    connContext,
+	}
+	tunnel.Serve(listener)
 }
 
-func (proxy *Proxy) writeToDB(buf *bytes.Buffer, addr string) {
-	a := make([]byte, 1024*1024*8)
-	for {
-		for {
-			select {
-			case <-time.After(3 * time.Second):
-				return
-			default:
-				_, err := buf.Read(a)
-				if err == io.EOF {
-					fmt.Println(addr, " - control line:", string(a))
-					return
-				}
-				if err := proxy.SaveBytes(a, addr); err != nil {
-					fmt.Println(addr, " error while saving", err.Error())
-				} else {
-					fmt.Println(addr, " success")
-				}
-			}
+// forward send r to the server, write the response to w
+// and save both of them. Requests and responses are changed
+// by rules and held in intercept mode before going further
+func (proxy *Proxy) forward(w http.ResponseWriter, r *http.Request) {
+	if isWebSocket(r) {
+		proxy.forwardWebSocket(w, r)
+		return
+	}
+	original := recordedHeader(r)
+
+	original = applyRequestRules(proxy.scopedRules(r, true), r, original)
+	settings := proxy.interceptSettings()
+	held := settings.Match(r.Method, r.URL.Hostname(), r.URL.Path)
+	if held {
+		var err error
+		if r, err = proxy.interceptRequest(w, r); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
 		}
 	}
-}
+	rdb := requestToDB(r, r.URL.Scheme == "https", original)
 
-func (proxy *Proxy) transfer(destination io.WriteCloser, source io.ReadCloser, save bool, address string) {
-	defer destination.Close()
-	defer source.Close()
+	outReq := r.Clone(r.Context())
+	outReq.RequestURI = ""
+	removeHopHeaders(outReq.Header)
 
-	buf := new(bytes.Buffer)
-	multiWriter := io.MultiWriter(destination, buf)
-	if save {
-		go proxy.writeToDB(buf, address)
+	start := time.Now()
+	resp, err := proxy.client.Do(outReq)
+	if err != nil {
+		go proxy.saveExchange(rdb, nil)
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer resp.Body.Close()
+	removeHopHeaders(resp.Header)
+
+	rules := proxy.scopedRules(r, false)
+	if len(rules) == 0 && (!held || !settings.Responses) {
+		body, size, err := copyResponseToWriter(w, resp)
+		if err != nil {
+			log.Printf("%s - error sending response: %v", r.URL.Host, err)
+		}
+		go proxy.saveExchange(rdb, responseToDB(resp, body, size, time.Since(start)))
+		return
 	}
-	io.Copy(multiWriter, ioutil.NopCloser(source))
-}
 
-func (proxy *Proxy) SaveBytes(info []byte, host string) error {
-	reader := bufio.NewReader(bytes.NewReader(info))
-	r, err := http.ReadRequest(reader)
+	// the response is saved as server sent it
+	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		println(host, " - error ReadRequest ", err.Error())
-		return err
+		log.Printf("Error reading body: %v", err)
 	}
-	r.URL.Host = host
-	saveRequest(r, true, proxy.db)
-	return nil
+	go proxy.saveExchange(rdb, responseToDB(resp, body, int64(len(body)), time.Since(start)))
+
+	body = applyResponseRules(rules, resp, body)
+	if held && settings.Responses {
+		if resp, err = proxy.interceptResponse(w, r, resp, body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+	}
+	if _, _, err = copyResponseToWriter(w, resp); err != nil {
+		log.Printf("%s - error sending response: %v", r.URL.Host, err)
+	}
+}
+
+// hopHeaders - headers, that belong to one connection and must not
+// be forwarded by proxy
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
 }
 
-func (proxy Proxy) RoundTrip(w http.ResponseWriter, req *http.Request) error {
-	resp, err := http.DefaultTransport.RoundTrip(req)
+func removeHopHeaders(header http.Header) {
+	for _, connection := range header["Connection"] {
+		for _, name := range strings.Split(connection, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				header.Del(name)
+			}
+		}
+	}
+	for _, name := range hopHeaders {
+		header.Del(name)
+	}
+}
+
+// Do send req, made from rdb, write the response to w and save
+// both of them as a new history entry. Its id is sent in
+// X-History-Id header
+func (proxy *Proxy) Do(w http.ResponseWriter, req *http.Request, rdb *models.RequestDB) error {
+	proxy.saveRequest(w, rdb)
+	removeHopHeaders(req.Header)
+
+	start := time.Now()
+	resp, err := proxy.client.Do(req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusServiceUnavailable)
 		return err
 	}
 	defer resp.Body.Close()
+	proxy.reply(w, rdb, resp, start)
+	return nil
+}
 
-	return copyResponseToWriter(w, resp)
+// saveRequest save rdb before sending and put its id to w
+func (proxy *Proxy) saveRequest(w http.ResponseWriter, rdb *models.RequestDB) {
+	if err := proxy.createRequest(rdb); err != nil {
+		log.Printf("Error, cant save request: %v", err)
+	} else {
+		w.Header().Set("X-History-Id", strconv.Itoa(rdb.ID))
+	}
 }
 
-func (proxy Proxy) Do(w http.ResponseWriter, req *http.Request) error {
-	resp, err := proxy.client.Do(req)
+// reply write resp to rdb to w and save it, if rdb was saved
+func (proxy *Proxy) reply(w http.ResponseWriter, rdb *models.RequestDB, resp *http.Response, start time.Time) {
+	removeHopHeaders(resp.Header)
+	body, size, err := copyResponseToWriter(w, resp)
 	if err != nil {
-		return err
+		log.Printf("%s - error sending response: %v", rdb.RemoteAddr, err)
+	}
+	if rdb.ID == 0 {
+		return
+	}
+
+	respDB := responseToDB(resp, body, size, time.Since(start))
+	respDB.RequestID = rdb.ID
+	if err := proxy.db.CreateResponse(respDB); err != nil {
+		log.Printf("Error, cant save response: %v", err)
 	}
-	defer resp.Body.Close()
-	err = copyResponseToWriter(w, resp)
-	return err
 }
 
-func copyResponseToWriter(w http.ResponseWriter, resp *http.Response) error {
+// copyResponseToWriter send response to w and return the first
+// maxBodySize bytes of the body that was sent and its whole size
+func copyResponseToWriter(w http.ResponseWriter, resp *http.Response) ([]byte, int64, error) {
 	copyHeader(w.Header(), resp.Header)
-	_, err := io.Copy(w, resp.Body)
-	return err
+	w.WriteHeader(resp.StatusCode)
+	body := new(bytes.Buffer)
+	size, err := io.Copy(w, io.TeeReader(resp.Body, &limitedWriter{body, maxBodySize}))
+	return body.Bytes(), size, err
 }
 
 func copyHeader(dst, src http.Header) {
@@ -239,13 +356,10 @@ func copyHeader(dst, src http.Header) {
 func (proxy *Proxy) ProxyHandler() func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		fmt.Println(r.URL.Hostname(), "- catched")
-		if r.Method != http.MethodConnect {
-			go saveRequest(r, false, proxy.db)
-		}
 		if r.Method == http.MethodConnect {
 			proxy.HandleTunneling(w, r)
 		} else {
-			proxy.RoundTrip(w, r)
+			proxy.forward(w, r)
 		}
 	}
 }
@@ -265,7 +379,6 @@ func (proxy *Proxy) saveString(request string) error {
 		rdb = &models.RequestDB{
 			Method:     headerElements[0],
 			RemoteAddr: headerElements[1],
-			Header:     make(map[string]string),
 		}
 		body      string
 		bodyBegan bool
@@ -277,7 +390,7 @@ func (proxy *Proxy) saveString(request string) error {
 		if !bodyBegan {
 			kv := strings.Split(row, ": ")
 			if len(kv) == 2 {
-				rdb.Header[kv[0]] = kv[1]
+				rdb.Header = append(rdb.Header, models.HeaderField{Name: kv[0], Value: kv[1]})
 			} else {
 				bodyBegan = true
 			}
@@ -285,35 +398,84 @@ func (proxy *Proxy) saveString(request string) error {
 			body += row
 		}
 	}
-	rdb.Body = body
+	rdb.Body = []byte(body)
 
-	return proxy.db.CreateRequest(rdb)
+	return proxy.createRequest(rdb)
 }
 
-func saveRequest(r *http.Request, https bool, db *database.DB) {
+// requestToDB make RequestDB from r. The body of r is read
+// and replaced, so r still can be sent. original - header lines
+// as client sent them, used to keep their order and casing
+func requestToDB(r *http.Request, https bool, original models.Header) *models.RequestDB {
 	var rdb = &models.RequestDB{
 		Method:     r.Method,
 		RemoteAddr: r.URL.Host,
-		Header:     make(map[string]string),
+		Path:       r.URL.EscapedPath(),
+		RawQuery:   r.URL.RawQuery,
+		Proto:      r.Proto,
 	}
 	if https {
 		rdb.Scheme = "https"
 	} else {
 		rdb.Scheme = "http"
 	}
-	for k, v := range r.Header {
-		rdb.Header[k] = v[0]
+	target := *r.URL
+	target.Scheme = rdb.Scheme
+	target.User = nil
+	target.Fragment = ""
+	target.RawFragment = ""
+	rdb.URL = target.String()
+	header := r.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
 	}
-	body, err := ioutil.ReadAll(r.Body)
-	if err != nil {
-		log.Printf("Error reading body: %v", err)
+	if r.Host != "" {
+		header.Set("Host", r.Host)
 	}
-	rdb.Body = string(body)
+	rdb.Header = models.OrderHeader(header, original)
+	rdb.Body, rdb.Truncated = truncateBody(readBody(r))
 	rdb.UserLogin = r.URL.User.Username()
 	rdb.UserPassword, _ = r.URL.User.Password()
+	return rdb
+}
 
-	err = db.CreateRequest(rdb)
-	if err != nil {
+// responseToDB make ResponseDB from resp, which body was already read.
+// size - the whole size of the body, body can be only its beginning
+func responseToDB(resp *http.Response, body []byte, size int64, duration time.Duration) *models.ResponseDB {
+	var respDB = &models.ResponseDB{
+		Proto:    resp.Proto,
+		Status:   resp.StatusCode,
+		Header:   models.HeaderFromHTTP(resp.Header),
+		Size:     size,
+		Duration: int64(duration / time.Millisecond),
+	}
+	respDB.Body, respDB.Truncated = truncateBody(body)
+	if size > int64(len(respDB.Body)) {
+		respDB.Truncated = true
+	}
+	return respDB
+}
+
+// truncateBody cut body to maxBodySize
+func truncateBody(body []byte) ([]byte, bool) {
+	if len(body) > maxBodySize {
+		return body[:maxBodySize], true
+	}
+	return body, false
+}
+
+// saveExchange save request and response to it. respDB can be nil,
+// if server did not answer
+func (proxy *Proxy) saveExchange(rdb *models.RequestDB, respDB *models.ResponseDB) {
+	if err := proxy.createRequest(rdb); err != nil {
 		log.Printf("Error, cant save request: %v", err)
+		return
+	}
+	if respDB == nil {
+		return
+	}
+	respDB.RequestID = rdb.ID
+	if err := proxy.db.CreateResponse(respDB); err != nil {
+		log.Printf("Error, cant save response: %v", err)
 	}
 }
@@ -0,0 +1,307 @@
+package proxy
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/database"
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+// testProxy start proxy with db and return client sending through it
+func testProxy(t *testing.T, db database.Store) (*Proxy, *http.Client) {
+	t.Helper()
+	proxy := &Proxy{
+		db: db,
+		client: &http.Client{
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+	proxy.reload()
+	server := httptest.NewServer(http.HandlerFunc(proxy.ProxyHandler()))
+	t.Cleanup(server.Close)
+
+	address, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(address)}}
+	return proxy, client
+}
+
+// memoryStore return empty memory store
+func memoryStore(t *testing.T) *database.MemoryDB {
+	t.Helper()
+	db, err := database.NewMemory(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// upstream start server answering "hello" and counting requests
+func upstream(t *testing.T, handle func(r *http.Request, body []byte)) (*httptest.Server, *int32) {
+	t.Helper()
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		body, _ := ioutil.ReadAll(r.Body)
+		if handle != nil {
+			handle(r, body)
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("hello"))
+	}))
+	t.Cleanup(server.Close)
+	return server, &count
+}
+
+// waitRequests wait until requests of project are saved, as proxy
+// saves them in background
+func waitRequests(t *testing.T, db database.Store, project, count int) []models.RequestDB {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		requestsDB, err := db.GetRequests(models.HistoryQuery{Limit: 10, Project: project})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(requestsDB.Requests) >= count {
+			return requestsDB.Requests
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("project %d has %d saved requests, want %d", project, len(requestsDB.Requests), count)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// waitHeld wait until a message of project is held and return it
+func waitHeld(t *testing.T, db database.Store, project int) models.InterceptItem {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		items, err := db.GetInterceptItems(project)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(items.Items) > 0 {
+			return items.Items[0]
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("no message of project %d is held", project)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func readAll(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestForwardSavesExchange(t *testing.T) {
+	db := memoryStore(t)
+	_, client := testProxy(t, db)
+	server, _ := upstream(t, nil)
+
+	resp, err := client.Post(server.URL+"/login?next=%2F", "text/plain", strings.NewReader("user=admin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := readAll(t, resp); resp.StatusCode != http.StatusOK || body != "hello" {
+		t.Errorf("client got %d %q, want 200 hello", resp.StatusCode, body)
+	}
+
+	request := waitRequests(t, db, models.DefaultProject, 1)[0]
+	if request.Method != "POST" || request.Path != "/login" || request.RawQuery != "next=%2F" ||
+		string(request.Body) != "user=admin" || request.URL != server.URL+"/login?next=%2F" {
+		t.Errorf("saved request %s %s ? %s with body %q, url %s",
+			request.Method, request.Path, request.RawQuery, request.Body, request.URL)
+	}
+	response, err := db.GetResponse(int32(request.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Status != http.StatusOK || string(response.Body) != "hello" || response.Size != 5 {
+		t.Errorf("saved response %d %q of size %d", response.Status, response.Body, response.Size)
+	}
+}
+
+func TestForwardRules(t *testing.T) {
+	db := memoryStore(t)
+	proxy, client := testProxy(t, db)
+	var header string
+	server, _ := upstream(t, func(r *http.Request, _ []byte) {
+		header = r.Header.Get("X-Rule")
+	})
+
+	rules := []models.RuleDB{
+		{Enabled: true, Target: models.RuleRequestHeader, Replace: "X-Rule: on"},
+		{Enabled: true, Target: models.RuleResponseBody, Match: "hello", Replace: "bye"},
+		{Enabled: false, Target: models.RuleResponseBody, Match: "bye", Replace: "disabled"},
+	}
+	for i := range rules {
+		if err := db.CreateRule(&rules[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	proxy.reload()
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := readAll(t, resp); body != "bye" {
+		t.Errorf("client got body %q, want it changed by rule", body)
+	}
+	if header != "on" {
+		t.Errorf("server got X-Rule %q, want header added by rule", header)
+	}
+	// the response is saved as server sent it
+	request := waitRequests(t, db, models.DefaultProject, 1)[0]
+	response, err := db.GetResponse(int32(request.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(response.Body) != "hello" {
+		t.Errorf("saved response body %q, want the original one", response.Body)
+	}
+}
+
+func TestForwardActiveProject(t *testing.T) {
+	db := memoryStore(t)
+	proxy, client := testProxy(t, db)
+	server, _ := upstream(t, nil)
+
+	project := &models.ProjectDB{Name: "engagement"}
+	if err := db.CreateProject(project); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.ActivateProject(int32(project.ID)); err != nil {
+		t.Fatal(err)
+	}
+	proxy.reload()
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readAll(t, resp)
+	waitRequests(t, db, project.ID, 1)
+	requestsDB, err := db.GetRequests(models.HistoryQuery{Limit: 10, Project: models.DefaultProject})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requestsDB.Requests) > 0 {
+		t.Errorf("request is saved to default project instead of the active one")
+	}
+}
+
+func TestIntercept(t *testing.T) {
+	tests := []struct {
+		name     string
+		state    string
+		modified string
+		status   int
+		path     string
+		body     string
+	}{
+		{"forward", models.InterceptForward, "", http.StatusOK, "/a", "original"},
+		{"forward edited", models.InterceptForward, "POST /edited HTTP/1.1\nHost: example.com\n\nchanged", http.StatusOK, "/edited", "changed"},
+		{"drop", models.InterceptDrop, "", http.StatusBadGateway, "", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := memoryStore(t)
+			proxy, client := testProxy(t, db)
+			var path, body string
+			server, count := upstream(t, func(r *http.Request, b []byte) {
+				path, body = r.URL.Path, string(b)
+			})
+			err := db.UpdateInterceptSettings(&models.InterceptSettings{ProjectID: models.DefaultProject, Enabled: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			proxy.reload()
+
+			done := make(chan *http.Response)
+			go func() {
+				resp, err := client.Post(server.URL+"/a", "text/plain", strings.NewReader("original"))
+				if err != nil {
+					t.Error(err)
+				}
+				done <- resp
+			}()
+			item := waitHeld(t, db, models.DefaultProject)
+			if item.Kind != models.InterceptRequest || !strings.HasSuffix(item.Raw, "original") {
+				t.Errorf("held %s %q, want the request", item.Kind, item.Raw)
+			}
+			if err = db.DecideInterceptItem(int32(item.ID), test.state, test.modified); err != nil {
+				t.Fatal(err)
+			}
+
+			resp := <-done
+			if resp == nil {
+				return
+			}
+			readAll(t, resp)
+			if resp.StatusCode != test.status {
+				t.Errorf("client got status %d, want %d", resp.StatusCode, test.status)
+			}
+			if test.path == "" {
+				if atomic.LoadInt32(count) > 0 {
+					t.Errorf("dropped request is sent to server")
+				}
+			} else if path != test.path || body != test.body {
+				t.Errorf("server got %s with body %q, want %s with %q", path, body, test.path, test.body)
+			}
+		})
+	}
+}
+
+// failingStore can not hold messages
+type failingStore struct {
+	database.Store
+}
+
+func (failingStore) CreateInterceptItem(item *models.InterceptItem) error {
+	return errors.New("store is broken")
+}
+
+func TestInterceptFailsClosed(t *testing.T) {
+	db := memoryStore(t)
+	err := db.UpdateInterceptSettings(&models.InterceptSettings{ProjectID: models.DefaultProject, Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, client := testProxy(t, failingStore{db})
+	server, count := upstream(t, nil)
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readAll(t, resp)
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("client got status %d, want %d", resp.StatusCode, http.StatusBadGateway)
+	}
+	if atomic.LoadInt32(count) > 0 {
+		t.Errorf("request, that was not held, is sent to server")
+	}
+}
@@ -0,0 +1,125 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+// RawTarget - where raw request is sent
+type RawTarget struct {
+	Host string
+	Port string
+	TLS  bool
+	// Insecure - do not verify certificate of the server
+	Insecure bool
+	Timeout  time.Duration
+}
+
+// SendRaw write raw to the target byte for byte and return the
+// response bytes as they came. If the server answers with several
+// responses at once, all of them are returned. On timeout, closed
+// connection or malformed response the bytes read so far are returned.
+// Only the first maxBodySize bytes of the answer are read
+func SendRaw(target RawTarget, raw []byte) ([]byte, error) {
+	var (
+		addr   = net.JoinHostPort(target.Host, target.Port)
+		dialer = &net.Dialer{Timeout: target.Timeout}
+		conn   net.Conn
+		err    error
+	)
+	if target.TLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{
+			ServerName:         target.Host,
+			NextProtos:         []string{"http/1.1"},
+			InsecureSkipVerify: target.Insecure,
+		})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(target.Timeout))
+
+	if _, err = conn.Write(raw); err != nil {
+		return nil, err
+	}
+
+	var (
+		received = new(bytes.Buffer)
+		reader   = bufio.NewReader(io.TeeReader(io.LimitReader(conn, maxBodySize), received))
+		request  *http.Request
+	)
+	// the method is needed to know if response has body
+	if r, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(raw))); err == nil {
+		request = r
+	}
+	for {
+		resp, err := http.ReadResponse(reader, request)
+		if err == nil {
+			_, err = io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if err != nil {
+			// not http or broken, give back all until the server stops
+			io.Copy(ioutil.Discard, reader)
+			if received.Len() == 0 {
+				return nil, err
+			}
+			return received.Bytes(), nil
+		}
+		// nothing is read ahead, so received is exactly the responses
+		if reader.Buffered() == 0 {
+			return received.Bytes(), nil
+		}
+	}
+}
+
+// DoRaw send rdb as HTTP/1.x request byte for byte, so the order and
+// casing of its header lines are kept, write the response to w and
+// save both of them the same way as Do
+func (proxy *Proxy) DoRaw(w http.ResponseWriter, rdb *models.RequestDB) error {
+	target := url.URL{Host: rdb.RemoteAddr}
+	if target.Hostname() == "" {
+		return errors.New("request has no address")
+	}
+	var rawTarget = RawTarget{
+		Host:    target.Hostname(),
+		Port:    target.Port(),
+		TLS:     rdb.Scheme == "https",
+		Timeout: proxy.client.Timeout,
+	}
+	if rawTarget.Port == "" {
+		if rawTarget.TLS {
+			rawTarget.Port = "443"
+		} else {
+			rawTarget.Port = "80"
+		}
+	}
+	raw := rdb.Raw()
+	proxy.saveRequest(w, rdb)
+
+	start := time.Now()
+	answer, err := SendRaw(rawTarget, raw)
+	if err != nil {
+		return err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(answer)), &http.Request{Method: rdb.Method})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	proxy.reply(w, rdb, resp, start)
+	return nil
+}
@@ -0,0 +1,116 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+// recordWindow - how many last read bytes recordConn keeps. It is
+// bigger than max header bytes and read ahead of http.Server together
+const recordWindow = 64 << 10
+
+// connKey - key of context value with connection of request
+type connKey struct{}
+
+// recordConn remember the last bytes read from HTTP/1.x connection,
+// because http.Request loses the order and casing of header lines
+type recordConn struct {
+	net.Conn
+
+	mutex    sync.Mutex
+	recorded []byte
+}
+
+func (conn *recordConn) Read(p []byte) (int, error) {
+	n, err := conn.Conn.Read(p)
+	if n > 0 {
+		conn.mutex.Lock()
+		conn.recorded = append(conn.recorded, p[:n]...)
+		if len(conn.recorded) > 2*recordWindow {
+			conn.recorded = append([]byte(nil), conn.recorded[len(conn.recorded)-recordWindow:]...)
+		}
+		conn.mutex.Unlock()
+	}
+	return n, err
+}
+
+// takeHeader find the header lines of request starting with
+// requestLine and forget recorded bytes up to their end
+func (conn *recordConn) takeHeader(requestLine string) models.Header {
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+
+	start := bytes.Index(conn.recorded, []byte(requestLine+"\r\n"))
+	if start < 0 {
+		start = bytes.Index(conn.recorded, []byte(requestLine+"\n"))
+	}
+	if start < 0 {
+		return nil
+	}
+	block := conn.recorded[start:]
+	end := bytes.Index(block, []byte("\r\n\r\n"))
+	if end < 0 {
+		end = bytes.Index(block, []byte("\n\n"))
+	}
+	if end < 0 {
+		return nil
+	}
+	conn.recorded = conn.recorded[start+end:]
+
+	var header models.Header
+	for i, line := range strings.Split(string(block[:end]), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if i == 0 || line == "" {
+			continue
+		}
+		// obsolete line folding continues the previous value
+		if (line[0] == ' ' || line[0] == '\t') && len(header) > 0 {
+			header[len(header)-1].Value += " " + strings.TrimSpace(line)
+			continue
+		}
+		kv := strings.SplitN(line, ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		header = append(header, models.HeaderField{
+			Name:  kv[0],
+			Value: strings.TrimSpace(kv[1]),
+		})
+	}
+	return header
+}
+
+// recordedHeader return header lines of r as client sent them,
+// or nil if they were not recorded
+func recordedHeader(r *http.Request) models.Header {
+	conn, ok := r.Context().Value(connKey{}).(*recordConn)
+	if !ok {
+		return nil
+	}
+	return conn.takeHeader(r.Method + " " + r.RequestURI + " " + r.Proto)
+}
+
+// connContext is http.Server.ConnContext hook, that makes
+// the connection available to handlers
+func connContext(ctx context.Context, conn net.Conn) context.Context {
+	return context.WithValue(ctx, connKey{}, conn)
+}
+
+// recordListener wraps accepted connections with recordConn
+type recordListener struct {
+	net.Listener
+}
+
+func (listener recordListener) Accept() (net.Conn, error) {
+	conn, err := listener.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &recordConn{Conn: conn}, nil
+}
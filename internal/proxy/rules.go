@@ -0,0 +1,146 @@
+package proxy
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+// loadRules reload enabled rules of project from database
+func (proxy *Proxy) loadRules(project int) {
+	rulesDB, err := proxy.db.GetRules(project)
+	if err != nil {
+		log.Printf("Error, cant load rules: %v", err)
+		return
+	}
+	rules := make([]models.RuleDB, 0, len(rulesDB.Rules))
+	for _, rule := range rulesDB.Rules {
+		if !rule.Enabled {
+			continue
+		}
+		if err := rule.Compile(); err != nil {
+			log.Printf("Error, rule %d is skipped: %v", rule.ID, err)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	proxy.mutex.Lock()
+	proxy.rules = rules
+	proxy.mutex.Unlock()
+}
+
+// scopedRules return rules for requests or responses of r
+func (proxy *Proxy) scopedRules(r *http.Request, request bool) []models.RuleDB {
+	proxy.mutex.RLock()
+	defer proxy.mutex.RUnlock()
+
+	var rules []models.RuleDB
+	for _, rule := range proxy.rules {
+		if rule.IsRequest() == request && rule.Scoped(r.Method, r.URL.Hostname(), r.URL.Path) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// applyRequestRules change r with rules in their order. original -
+// header lines as client sent them, header rules are applied to
+// them to keep order and casing. The changed lines are returned
+func applyRequestRules(rules []models.RuleDB, r *http.Request, original models.Header) models.Header {
+	for i := range rules {
+		rule := &rules[i]
+		switch rule.Target {
+		case models.RuleRequestURL:
+			target, err := url.Parse(rule.Apply(r.URL.String()))
+			if err != nil {
+				log.Printf("Error, rule %d made invalid url: %v", rule.ID, err)
+				continue
+			}
+			if target.Host != r.URL.Host {
+				r.Host = target.Host
+			}
+			r.URL = target
+		case models.RuleRequestHeader:
+			header := r.Header.Clone()
+			header.Set("Host", r.Host)
+			original = applyHeaderRule(rule, models.OrderHeader(header, original))
+			r.Host = original.Get("Host")
+			r.Header = original.Del("Host").HTTP()
+		case models.RuleRequestBody:
+			body := []byte(rule.Apply(string(readBody(r))))
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+			r.ContentLength = int64(len(body))
+			r.TransferEncoding = nil
+			r.Header.Del("Transfer-Encoding")
+			if r.Header.Get("Content-Length") != "" || len(body) > 0 {
+				r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+			}
+		}
+	}
+	return original
+}
+
+// applyResponseRules change resp with rules in their order.
+// body is the read body of resp, the changed one is returned
+func applyResponseRules(rules []models.RuleDB, resp *http.Response, body []byte) []byte {
+	for i := range rules {
+		rule := &rules[i]
+		switch rule.Target {
+		case models.RuleResponseStatus:
+			status := rule.Apply(resp.Status)
+			code, err := strconv.Atoi(strings.SplitN(status, " ", 2)[0])
+			if err != nil || code < 100 || code > 999 {
+				log.Printf("Error, rule %d made invalid status %q", rule.ID, status)
+				continue
+			}
+			resp.Status = status
+			resp.StatusCode = code
+		case models.RuleResponseHeader:
+			resp.Header = applyHeaderRule(rule, models.HeaderFromHTTP(resp.Header)).HTTP()
+		case models.RuleResponseBody:
+			body = []byte(rule.Apply(string(body)))
+			resp.ContentLength = int64(len(body))
+			resp.TransferEncoding = nil
+			resp.Header.Del("Transfer-Encoding")
+			resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		}
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body
+}
+
+// applyHeaderRule apply rule to every "Name: value" line of header.
+// Lines keep their places, lines changed to empty string are removed
+func applyHeaderRule(rule *models.RuleDB, header models.Header) models.Header {
+	var changed = make(models.Header, 0, len(header)+1)
+	for _, field := range header {
+		line := field.Name + ": " + field.Value
+		if rule.Match != "" {
+			line = rule.Apply(line)
+		}
+		if field, ok := headerField(line); ok {
+			changed = append(changed, field)
+		}
+	}
+	if rule.Match == "" {
+		if field, ok := headerField(rule.Replace); ok {
+			changed = append(changed, field)
+		}
+	}
+	return changed
+}
+
+// headerField parse "Name: value" line
+func headerField(line string) (models.HeaderField, bool) {
+	kv := strings.SplitN(line, ":", 2)
+	if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+		return models.HeaderField{}, false
+	}
+	return models.HeaderField{Name: strings.TrimSpace(kv[0]), Value: strings.TrimSpace(kv[1])}, true
+}
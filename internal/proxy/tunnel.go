@@ -0,0 +1,66 @@
+package proxy
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// tunnelHandler serve decrypted requests of the tunnel to host
+// the same way as plain http requests
+func (proxy *Proxy) tunnelHandler(host string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.URL.Scheme = "https"
+		r.URL.Host = host
+		proxy.forward(w, r)
+	}
+}
+
+// connListener is net.Listener with the only connection. Accept
+// returns it once and then blocks until the connection is closed,
+// so http.Server can serve the hijacked connection of a tunnel
+type connListener struct {
+	conn      net.Conn
+	conns     chan net.Conn
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newConnListener(conn net.Conn) *connListener {
+	listener := &connListener{
+		conn:  conn,
+		conns: make(chan net.Conn, 1),
+		done:  make(chan struct{}),
+	}
+	listener.conns <- conn
+	return listener
+}
+
+func (listener *connListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-listener.conns:
+		return conn, nil
+	case <-listener.done:
+		return nil, io.EOF
+	}
+}
+
+func (listener *connListener) Close() error {
+	listener.closeOnce.Do(func() {
+		close(listener.done)
+	})
+	return nil
+}
+
+func (listener *connListener) Addr() net.Addr {
+	return listener.conn.LocalAddr()
+}
+
+// connState is http.Server.ConnState hook, that stops listener
+// when its connection is gone
+func (listener *connListener) connState(conn net.Conn, state http.ConnState) {
+	if state == http.StateClosed || state == http.StateHijacked {
+		listener.Close()
+	}
+}
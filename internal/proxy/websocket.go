@@ -0,0 +1,298 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+const (
+	// maxMessageSize - how many bytes of one websocket message are saved.
+	// Longer messages are relayed fully but saved truncated
+	maxMessageSize = 1 << 20
+	// messagesQueue - how many messages can wait for saving
+	messagesQueue = 64
+)
+
+// websocket opcodes
+const (
+	opContinuation = 0
+	// opClose and greater opcodes are control frames
+	opClose = 8
+)
+
+func isWebSocket(r *http.Request) bool {
+	return headerContains(r.Header, "Connection", "upgrade") &&
+		strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// headerContains check if comma separated header name has token
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header[http.CanonicalHeaderKey(name)] {
+		for _, v := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// forwardWebSocket pass the handshake to the server and, if it
+// agrees, relay frames in both directions saving every message
+func (proxy *Proxy) forwardWebSocket(w http.ResponseWriter, r *http.Request) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket is not supported over "+r.Proto, http.StatusHTTPVersionNotSupported)
+		return
+	}
+	rdb := requestToDB(r, r.URL.Scheme == "https", recordedHeader(r))
+
+	serverConn, err := proxy.dialWebSocket(r.URL)
+	if err != nil {
+		go proxy.saveExchange(rdb, nil)
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	outReq := r.Clone(r.Context())
+	outReq.Header.Del("Proxy-Connection")
+	outReq.Header.Del("Proxy-Authorization")
+	// without compression the saved messages stay readable
+	outReq.Header.Del("Sec-WebSocket-Extensions")
+
+	start := time.Now()
+	serverReader := bufio.NewReader(serverConn)
+	resp, err := writeHandshake(outReq, serverConn, serverReader)
+	if err != nil {
+		serverConn.Close()
+		go proxy.saveExchange(rdb, nil)
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		defer serverConn.Close()
+		removeHopHeaders(resp.Header)
+		body, size, _ := copyResponseToWriter(w, resp)
+		go proxy.saveExchange(rdb, responseToDB(resp, body, size, time.Since(start)))
+		return
+	}
+
+	clientConn, clientRW, err := hijacker.Hijack()
+	if err != nil {
+		serverConn.Close()
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	clientConn.SetDeadline(time.Time{})
+
+	var head bytes.Buffer
+	head.WriteString("HTTP/1.1 " + resp.Status + "\r\n")
+	resp.Header.Write(&head)
+	head.WriteString("\r\n")
+	if _, err = clientConn.Write(head.Bytes()); err != nil {
+		clientConn.Close()
+		serverConn.Close()
+		return
+	}
+	proxy.saveExchange(rdb, responseToDB(resp, nil, 0, time.Since(start)))
+
+	messages := make(chan *models.WebSocketMessage, messagesQueue)
+	go proxy.saveMessages(rdb.ID, messages)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	relay := func(source io.Reader, destination net.Conn, direction string) {
+		defer wg.Done()
+		err := relayFrames(source, destination, direction, messages)
+		if err != nil && err != io.EOF && !errors.Is(err, net.ErrClosed) {
+			log.Printf("%s - websocket closed: %v", r.URL.Host, err)
+		}
+		clientConn.Close()
+		serverConn.Close()
+	}
+	go relay(clientRW.Reader, serverConn, models.WebSocketFromClient)
+	go relay(serverReader, clientConn, models.WebSocketFromServer)
+	wg.Wait()
+	close(messages)
+}
+
+// dialWebSocket open connection to the server of websocket handshake u
+func (proxy *Proxy) dialWebSocket(u *url.URL) (net.Conn, error) {
+	var (
+		dialer = &net.Dialer{Timeout: proxy.client.Timeout}
+		addr   = u.Host
+	)
+	if u.Port() == "" {
+		if u.Scheme == "https" {
+			addr = net.JoinHostPort(u.Hostname(), "443")
+		} else {
+			addr = net.JoinHostPort(u.Hostname(), "80")
+		}
+	}
+	if u.Scheme != "https" {
+		return dialer.Dial("tcp", addr)
+	}
+	return tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{
+		ServerName: u.Hostname(),
+		NextProtos: []string{"http/1.1"},
+	})
+}
+
+func writeHandshake(r *http.Request, conn net.Conn, reader *bufio.Reader) (*http.Response, error) {
+	if err := r.Write(conn); err != nil {
+		return nil, err
+	}
+	return http.ReadResponse(reader, r)
+}
+
+// saveMessages save websocket messages of the connection opened
+// by request with requestID
+func (proxy *Proxy) saveMessages(requestID int, messages <-chan *models.WebSocketMessage) {
+	for message := range messages {
+		if requestID == 0 {
+			continue
+		}
+		message.RequestID = requestID
+		if err := proxy.db.CreateWebSocketMessage(message); err != nil {
+			log.Printf("Error, cant save websocket message: %v", err)
+		}
+	}
+}
+
+// relayFrames copy websocket frames from source to destination
+// and send every message from them to messages. Fragmented messages
+// are joined, control frames are sent as they are
+func relayFrames(source io.Reader, destination io.Writer, direction string,
+	messages chan<- *models.WebSocketMessage) error {
+	var message *models.WebSocketMessage
+	for {
+		fin, opcode, payload, err := relayFrame(source, destination)
+		if err != nil {
+			return err
+		}
+		if opcode >= opClose {
+			messages <- newMessage(direction, opcode, payload)
+			continue
+		}
+		if opcode != opContinuation || message == nil {
+			message = newMessage(direction, opcode, nil)
+		}
+		if room := maxMessageSize - len(message.Payload); room > 0 {
+			if len(payload) > room {
+				payload = payload[:room]
+			}
+			message.Payload = append(message.Payload, payload...)
+		}
+		if fin {
+			messages <- message
+			message = nil
+		}
+	}
+}
+
+func newMessage(direction string, opcode int, payload []byte) *models.WebSocketMessage {
+	return &models.WebSocketMessage{
+		Direction: direction,
+		Opcode:    opcode,
+		Payload:   payload,
+		Add:       time.Now(),
+	}
+}
+
+// relayFrame copy one frame from source to destination and return
+// its unmasked payload, cut to maxMessageSize
+func relayFrame(source io.Reader, destination io.Writer) (fin bool, opcode int, payload []byte, err error) {
+	var head = make([]byte, 2, 14)
+	if _, err = io.ReadFull(source, head); err != nil {
+		return
+	}
+	fin = head[0]&0x80 != 0
+	opcode = int(head[0] & 0x0f)
+	masked := head[1]&0x80 != 0
+
+	var (
+		length = uint64(head[1] & 0x7f)
+		extra  int
+	)
+	switch length {
+	case 126:
+		extra = 2
+	case 127:
+		extra = 8
+	}
+	if masked {
+		extra += 4
+	}
+	head = head[:2+extra]
+	if _, err = io.ReadFull(source, head[2:]); err != nil {
+		return
+	}
+	switch length {
+	case 126:
+		length = uint64(binary.BigEndian.Uint16(head[2:4]))
+	case 127:
+		length = binary.BigEndian.Uint64(head[2:10])
+	}
+	if _, err = destination.Write(head); err != nil {
+		return
+	}
+
+	var saved bytes.Buffer
+	keep := length
+	if keep > maxMessageSize {
+		keep = maxMessageSize
+	}
+	body := io.TeeReader(io.LimitReader(source, int64(length)), &limitedWriter{&saved, int(keep)})
+	var n int64
+	if n, err = io.Copy(destination, body); err != nil {
+		return
+	}
+	if uint64(n) != length {
+		err = io.ErrUnexpectedEOF
+		return
+	}
+	payload = saved.Bytes()
+	if masked {
+		key := head[len(head)-4:]
+		for i := range payload {
+			payload[i] ^= key[i%4]
+		}
+	}
+	return
+}
+
+// limitedWriter write first left bytes to writer and drop the rest
+type limitedWriter struct {
+	writer io.Writer
+	left   int
+}
+
+func (limited *limitedWriter) Write(p []byte) (int, error) {
+	if limited.left > 0 {
+		part := p
+		if len(part) > limited.left {
+			part = part[:limited.left]
+		}
+		n, err := limited.writer.Write(part)
+		limited.left -= n
+		if err != nil {
+			return n, err
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,68 @@
+package repeater
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/archive"
+)
+
+// ExportArchive send project with all its traffic, rules and
+// intercept settings as one gzip compressed tar file
+func (repeater *Repeater) ExportArchive(rw http.ResponseWriter, r *http.Request) {
+	const place = "ExportArchive"
+
+	project, ok := repeater.projectOf(rw, r, place)
+	if !ok {
+		return
+	}
+
+	liftWriteDeadline(rw, place)
+	rw.Header().Set("Content-Type", "application/gzip")
+	rw.Header().Set("Content-Disposition",
+		`attachment; filename="project-`+strconv.Itoa(project.ID)+`.tar.gz"`)
+	// the answer is already started, so errors can be only logged
+	if err := archive.Export(rw, repeater.db, project); err != nil {
+		log.Printf("Error, cant export project %d: %v", project.ID, err)
+	}
+}
+
+// ImportArchive make new project from archive in body.
+// Query: name - name of the new project instead of the name
+// from archive, rules=false - do not add rules of archive
+func (repeater *Repeater) ImportArchive(rw http.ResponseWriter, r *http.Request) {
+	const place = "ImportArchive"
+
+	var (
+		values  = r.URL.Query()
+		options = archive.Options{Name: values.Get("name")}
+		err     error
+	)
+	if value := values.Get("rules"); value != "" {
+		var rules bool
+		if rules, err = strconv.ParseBool(value); err != nil {
+			SendResult(rw, NewResult(http.StatusBadRequest, place, nil, errors.New("rules must be true or false")))
+			return
+		}
+		options.SkipRules = !rules
+	}
+
+	summary, err := archive.Import(http.MaxBytesReader(rw, r.Body, maxImportSize), repeater.db, options)
+	if err != nil {
+		SendResult(rw, NewResult(http.StatusBadRequest, place, nil, err))
+		return
+	}
+	SendResult(rw, NewResult(http.StatusCreated, place, summary, nil))
+}
+
+// liftWriteDeadline remove WriteTimeout of server for the answer to r,
+// so that big exports are not cut off in the middle
+func liftWriteDeadline(rw http.ResponseWriter, place string) {
+	err := http.NewResponseController(rw).SetWriteDeadline(time.Time{})
+	if err != nil {
+		log.Printf("Error in %s, cant remove write deadline: %v", place, err)
+	}
+}
@@ -0,0 +1,81 @@
+package repeater
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+// GetBody write the body of the response to request with id as it is.
+// Query: part=request to get the body of request, decoded=true to
+// undo Content-Encoding
+func (repeater *Repeater) GetBody(rw http.ResponseWriter, r *http.Request) {
+	const place = "GetBody"
+
+	request, _, ok := repeater.requestOf(rw, r, place)
+	if !ok {
+		return
+	}
+	var (
+		decoded bool
+		err     error
+	)
+	if value := r.URL.Query().Get("decoded"); value != "" {
+		if decoded, err = strconv.ParseBool(value); err != nil {
+			SendResult(rw, NewResult(http.StatusBadRequest, place, nil, errors.New("decoded must be true or false")))
+			return
+		}
+	}
+
+	var (
+		body      []byte
+		header    models.Header
+		truncated bool
+	)
+	switch r.URL.Query().Get("part") {
+	case "request":
+		body, header, truncated = request.Body, request.Header, request.Truncated
+	case "", "response":
+		response, err := repeater.db.GetResponse(int32(request.ID))
+		if err != nil {
+			sendNotFoundOr(rw, place, err, "request has no response")
+			return
+		}
+		body, header, truncated = response.Body, response.Header, response.Truncated
+	default:
+		SendResult(rw, NewResult(http.StatusBadRequest, place, nil, errors.New("part must be request or response")))
+		return
+	}
+
+	if decoded {
+		// the end of truncated body is missing, so it is decoded partly
+		body, err = models.DecodeBody(body, header.Get("Content-Encoding"))
+		if err != nil && !(truncated && err == io.ErrUnexpectedEOF) {
+			SendResult(rw, NewResult(http.StatusUnprocessableEntity, place, nil, err))
+			return
+		}
+	} else if encoding := header.Get("Content-Encoding"); encoding != "" {
+		rw.Header().Set("X-Content-Encoding", encoding)
+	}
+	if contentType := header.Get("Content-Type"); contentType != "" {
+		rw.Header().Set("Content-Type", contentType)
+	} else {
+		rw.Header().Set("Content-Type", "application/octet-stream")
+	}
+	if truncated {
+		rw.Header().Set("X-Body-Truncated", "true")
+	}
+	rw.Write(body)
+}
+
+func sendNotFoundOr(rw http.ResponseWriter, place string, err error, notFound string) {
+	if err == sql.ErrNoRows {
+		SendResult(rw, NewResult(http.StatusNotFound, place, nil, errors.New(notFound)))
+	} else {
+		SendResult(rw, NewResult(http.StatusInternalServerError, place, nil, err))
+	}
+}
@@ -0,0 +1,136 @@
+package repeater
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/har"
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+const (
+	// exportPage - how many requests are read from database at once on export
+	exportPage = 500
+	// maxImportSize - max size of imported archive
+	maxImportSize = 256 << 20
+)
+
+// ExportHAR send requests of history with their responses as HTTP
+// Archive. Parameters are the same as for history, but without limit
+// all matching requests are exported. Project can be set in path
+func (repeater *Repeater) ExportHAR(rw http.ResponseWriter, r *http.Request) {
+	const place = "ExportHAR"
+
+	query, _, ok := repeater.historyQuery(rw, r, place)
+	if !ok {
+		return
+	}
+	liftWriteDeadline(rw, place)
+	requests, err := repeater.historyRequests(query, r.URL.Query().Get("limit") != "")
+	if err != nil {
+		SendResult(rw, NewResult(http.StatusInternalServerError, place, nil, err))
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Header().Set("Content-Disposition", `attachment; filename="history.har"`)
+	SendResult(rw, NewResult(http.StatusOK, place, har.Export(requests), nil))
+}
+
+// ImportHAR save entries of HTTP Archive from body to history
+// of the project, set by project parameter, or of the active one.
+// Entries that can not be saved are reported with their errors
+func (repeater *Repeater) ImportHAR(rw http.ResponseWriter, r *http.Request) {
+	const place = "ImportHAR"
+
+	project, ok := repeater.openProject(rw, r, place)
+	if !ok {
+		return
+	}
+	var archive har.HAR
+	decoder := json.NewDecoder(http.MaxBytesReader(rw, r.Body, maxImportSize))
+	if err := decoder.Decode(&archive); err != nil {
+		SendResult(rw, NewResult(http.StatusBadRequest, place, nil, errors.New("body must be HAR: "+err.Error())))
+		return
+	}
+
+	var result = &models.ImportResult{Entries: make([]models.ImportStatus, 0, len(archive.Log.Entries))}
+	for i := range archive.Log.Entries {
+		var status = models.ImportStatus{Index: i}
+		rdb, err := archive.Log.Entries[i].ToRequest()
+		if err == nil {
+			rdb.ProjectID = project.ID
+			err = repeater.saveImported(rdb)
+			status.ID = rdb.ID
+		}
+		result.Add(status, err)
+	}
+	SendResult(rw, NewResult(http.StatusOK, place, result, nil))
+}
+
+// saveImported save request with its response. If response can
+// not be saved, the request is removed, so failed entry can be
+// imported again without duplicates
+func (repeater *Repeater) saveImported(rdb *models.RequestDB) error {
+	if err := repeater.db.CreateRequest(rdb); err != nil {
+		return err
+	}
+	if rdb.Response == nil {
+		return nil
+	}
+	rdb.Response.RequestID = rdb.ID
+	err := repeater.db.CreateResponse(rdb.Response)
+	if err != nil {
+		if deleteErr := repeater.db.DeleteRequest(int32(rdb.ID)); deleteErr != nil {
+			log.Printf("Error, cant remove request %d of failed import: %v", rdb.ID, deleteErr)
+		}
+		rdb.ID = 0
+	}
+	return err
+}
+
+// historyRequests return requests matching query with their responses
+// going through all pages. If limited is false, limit of query is ignored
+func (repeater *Repeater) historyRequests(query models.HistoryQuery, limited bool) ([]models.RequestDB, error) {
+	var (
+		requests = make([]models.RequestDB, 0)
+		left     = query.Limit
+	)
+	for !limited || left > 0 {
+		page := query
+		page.Limit = exportPage
+		if limited && left < exportPage {
+			page.Limit = left
+		}
+		requestsDB, err := repeater.db.GetRequests(page)
+		if err != nil {
+			return nil, err
+		}
+
+		ids := make([]int, 0, len(requestsDB.Requests))
+		for _, request := range requestsDB.Requests {
+			ids = append(ids, request.ID)
+		}
+		responses, err := repeater.db.GetResponses(ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, request := range requestsDB.Requests {
+			request.Response = responses[request.ID]
+			requests = append(requests, request)
+		}
+
+		left -= len(requestsDB.Requests)
+		if requestsDB.Next == nil {
+			break
+		}
+		if query.Desc {
+			query.BeforeID = *requestsDB.Next
+		} else {
+			query.AfterID = *requestsDB.Next
+		}
+	}
+	return requests, nil
+}
@@ -0,0 +1,152 @@
+package repeater
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+const (
+	// defaultLimit - how many requests of history are returned by default
+	defaultLimit = 20
+	// maxLimit - how many requests of history can be returned on one page.
+	// Exports go through all pages, so it does not limit them
+	maxLimit = 1000
+)
+
+// errLimit is returned, when page of history is bigger than maxLimit
+var errLimit = errors.New("limit must not be greater than " + strconv.Itoa(maxLimit))
+
+// historyQueryFromURL make query of history from parameters of r.
+// Simple parameters are joined with AND, the filter parameter can
+// have json HistoryFilter with AND/OR groups
+func historyQueryFromURL(r *http.Request) (models.HistoryQuery, error) {
+	var (
+		values = r.URL.Query()
+		query  = models.HistoryQuery{Desc: true, Limit: defaultLimit}
+		filter = &query.Filter
+		err    error
+	)
+	if value := values.Get("filter"); value != "" {
+		if err = json.Unmarshal([]byte(value), filter); err != nil {
+			return query, errors.New("filter must be json: " + err.Error())
+		}
+	}
+	switch values.Get("last") {
+	case "false", "0", "-":
+		query.Desc = false
+	}
+	if value := values.Get("limit"); value != "" {
+		if query.Limit, err = strconv.Atoi(value); err != nil || query.Limit <= 0 {
+			return query, errors.New("limit must be positive number")
+		}
+	}
+
+	if query.AfterID, err = idParameter(values, "after_id"); err != nil {
+		return query, err
+	}
+	if query.BeforeID, err = idParameter(values, "before_id"); err != nil {
+		return query, err
+	}
+
+	var extra models.HistoryFilter
+	extra.Scheme = values.Get("scheme")
+	extra.Host = values.Get("host")
+	extra.HostMatch = values.Get("host_match")
+	extra.PathPrefix = values.Get("path")
+	extra.Methods = listParameter(values, "method")
+	extra.BodyContains = values.Get("body")
+	extra.HeaderContains = values.Get("header")
+	extra.ContentType = values.Get("content_type")
+	extra.Tags = listParameter(values, "tag")
+	for _, status := range listParameter(values, "status") {
+		statusRange, err := models.ParseStatusRange(status)
+		if err != nil {
+			return query, err
+		}
+		extra.Status = append(extra.Status, statusRange)
+	}
+	if extra.From, err = timeParameter(values, "from"); err != nil {
+		return query, err
+	}
+	if extra.To, err = timeParameter(values, "to"); err != nil {
+		return query, err
+	}
+	filter.All = append(filter.All, extra)
+
+	return query, filter.Validate()
+}
+
+// idParameter parse optional positive id
+func idParameter(values url.Values, name string) (int, error) {
+	value := values.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	id, err := strconv.Atoi(value)
+	if err != nil || id <= 0 {
+		return 0, errors.New(name + " must be positive number")
+	}
+	return id, nil
+}
+
+// listParameter return values of repeated or comma separated parameter
+func listParameter(values url.Values, name string) []string {
+	var list []string
+	for _, value := range values[name] {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				list = append(list, v)
+			}
+		}
+	}
+	return list
+}
+
+// timeParameter parse RFC 3339 time or unix seconds
+func timeParameter(values url.Values, name string) (*time.Time, error) {
+	value := values.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		t := time.Unix(seconds, 0)
+		return &t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, errors.New(name + " must be RFC 3339 time or unix seconds")
+	}
+	return &t, nil
+}
+
+// SetTags replace tags of request with id by json array from body
+func (repeater *Repeater) SetTags(rw http.ResponseWriter, r *http.Request) {
+	const place = "SetTags"
+
+	request, _, ok := repeater.requestOf(rw, r, place)
+	if !ok {
+		return
+	}
+	var tags models.Tags
+	body, err := ioutil.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &tags)
+	}
+	if err != nil {
+		SendResult(rw, NewResult(http.StatusBadRequest, place, nil, err))
+		return
+	}
+	if err = repeater.db.SetRequestTags(int32(request.ID), tags); err != nil {
+		sendNotFoundOr(rw, place, err, "no such request")
+		return
+	}
+	SendResult(rw, NewResult(http.StatusOK, place, tags, nil))
+}
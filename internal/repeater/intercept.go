@@ -0,0 +1,121 @@
+package repeater
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+var errNoHeld = errors.New("no such held message")
+
+// GetInterceptSettings return intercept settings of project from
+// parameter or of the active one
+func (repeater *Repeater) GetInterceptSettings(rw http.ResponseWriter, r *http.Request) {
+	const place = "GetInterceptSettings"
+
+	project, ok := repeater.projectOf(rw, r, place)
+	if !ok {
+		return
+	}
+
+	settings, err := repeater.db.GetInterceptSettings(project.ID)
+	if err != nil {
+		SendResult(rw, NewResult(http.StatusInternalServerError, place, nil, err))
+	} else {
+		SendResult(rw, NewResult(http.StatusOK, place, settings, err))
+	}
+}
+
+func (repeater *Repeater) UpdateInterceptSettings(rw http.ResponseWriter, r *http.Request) {
+	const place = "UpdateInterceptSettings"
+
+	project, ok := repeater.projectOf(rw, r, place)
+	if !ok {
+		return
+	}
+	var settings models.InterceptSettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		SendResult(rw, NewResult(http.StatusBadRequest, place, nil, err))
+		return
+	}
+	settings.ProjectID = project.ID
+
+	err := repeater.db.UpdateInterceptSettings(&settings)
+	if err != nil {
+		SendResult(rw, NewResult(http.StatusInternalServerError, place, nil, err))
+	} else {
+		SendResult(rw, NewResult(http.StatusOK, place, &settings, err))
+	}
+}
+
+func (repeater *Repeater) GetInterceptQueue(rw http.ResponseWriter, r *http.Request) {
+	const place = "GetInterceptQueue"
+
+	project, ok := repeater.projectOf(rw, r, place)
+	if !ok {
+		return
+	}
+
+	items, err := repeater.db.GetInterceptItems(project.ID)
+	if err != nil {
+		SendResult(rw, NewResult(http.StatusInternalServerError, place, nil, err))
+	} else {
+		SendResult(rw, NewResult(http.StatusOK, place, items, err))
+	}
+}
+
+// ForwardIntercepted let the held message go. The body of request,
+// if not empty, is the edited raw message to send instead
+func (repeater *Repeater) ForwardIntercepted(rw http.ResponseWriter, r *http.Request) {
+	const place = "ForwardIntercepted"
+
+	id, err := IDFromPath(r, "id")
+	if err != nil {
+		SendResult(rw, NewResult(http.StatusBadRequest, place, nil, err))
+		return
+	}
+	modified, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		SendResult(rw, NewResult(http.StatusBadRequest, place, nil, err))
+		return
+	}
+	repeater.decideIntercepted(rw, r, place, id, models.InterceptForward, string(modified))
+}
+
+func (repeater *Repeater) DropIntercepted(rw http.ResponseWriter, r *http.Request) {
+	const place = "DropIntercepted"
+
+	id, err := IDFromPath(r, "id")
+	if err != nil {
+		SendResult(rw, NewResult(http.StatusBadRequest, place, nil, err))
+		return
+	}
+	repeater.decideIntercepted(rw, r, place, id, models.InterceptDrop, "")
+}
+
+// decideIntercepted set state of held message with id, if it belongs
+// to project of r. Messages of other projects are not found
+func (repeater *Repeater) decideIntercepted(rw http.ResponseWriter, r *http.Request, place string, id int32, state, modified string) {
+	project, ok := repeater.projectOf(rw, r, place)
+	if !ok {
+		return
+	}
+	item, err := repeater.db.GetInterceptItem(id)
+	if err == nil && item.ProjectID != project.ID {
+		err = sql.ErrNoRows
+	}
+	if err == nil {
+		err = repeater.db.DecideInterceptItem(id, state, modified)
+	}
+	if err == sql.ErrNoRows {
+		SendResult(rw, NewResult(http.StatusNotFound, place, nil, errNoHeld))
+	} else if err != nil {
+		SendResult(rw, NewResult(http.StatusInternalServerError, place, nil, err))
+	} else {
+		SendResult(rw, NewResult(http.StatusOK, place, nil, err))
+	}
+}
@@ -0,0 +1,68 @@
+package repeater
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/postman"
+)
+
+// ExportPostman send requests of history as Postman collection
+// with folders of hosts and paths. Parameters are the same as
+// for export to HAR. Query: name - name of collection, the name
+// of project by default
+func (repeater *Repeater) ExportPostman(rw http.ResponseWriter, r *http.Request) {
+	const place = "ExportPostman"
+
+	query, project, ok := repeater.historyQuery(rw, r, place)
+	if !ok {
+		return
+	}
+	liftWriteDeadline(rw, place)
+	requests, err := repeater.historyRequests(query, r.URL.Query().Get("limit") != "")
+	if err != nil {
+		SendResult(rw, NewResult(http.StatusInternalServerError, place, nil, err))
+		return
+	}
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		name = project.Name
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Header().Set("Content-Disposition", `attachment; filename="history.postman_collection.json"`)
+	SendResult(rw, NewResult(http.StatusOK, place, postman.Export(name, requests), nil))
+}
+
+// ImportPostman save requests of Postman collection from body to
+// history of the project as ImportHAR, reporting failed ones
+func (repeater *Repeater) ImportPostman(rw http.ResponseWriter, r *http.Request) {
+	const place = "ImportPostman"
+
+	project, ok := repeater.openProject(rw, r, place)
+	if !ok {
+		return
+	}
+	var collection postman.Collection
+	decoder := json.NewDecoder(http.MaxBytesReader(rw, r.Body, maxImportSize))
+	if err := decoder.Decode(&collection); err != nil {
+		SendResult(rw, NewResult(http.StatusBadRequest, place, nil, errors.New("body must be Postman collection: "+err.Error())))
+		return
+	}
+
+	entries := collection.Entries()
+	var result = &models.ImportResult{Entries: make([]models.ImportStatus, 0, len(entries))}
+	for i := range entries {
+		var status = models.ImportStatus{Index: i, Name: entries[i].Name}
+		rdb, err := entries[i].ToRequest()
+		if err == nil {
+			rdb.ProjectID = project.ID
+			err = repeater.db.CreateRequest(rdb)
+			status.ID = rdb.ID
+		}
+		result.Add(status, err)
+	}
+	SendResult(rw, NewResult(http.StatusOK, place, result, nil))
+}
@@ -0,0 +1,234 @@
+package repeater
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/database"
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+	"github.com/gorilla/mux"
+)
+
+var (
+	errNoProject       = errors.New("no such project")
+	errArchivedProject = errors.New("project is archived")
+	errActiveProject   = errors.New("project is active, activate another one first")
+)
+
+func (repeater *Repeater) GetProjects(rw http.ResponseWriter, r *http.Request) {
+	const place = "GetProjects"
+
+	projects, err := repeater.db.GetProjects()
+	if err != nil {
+		SendResult(rw, NewResult(http.StatusInternalServerError, place, nil, err))
+	} else {
+		SendResult(rw, NewResult(http.StatusOK, place, projects, err))
+	}
+}
+
+// CreateProject add project from json body. New project is not
+// active, traffic goes to it after activation
+func (repeater *Repeater) CreateProject(rw http.ResponseWriter, r *http.Request) {
+	const place = "CreateProject"
+
+	var project = &models.ProjectDB{}
+	if err := projectFromBody(r, project); err != nil {
+		SendResult(rw, NewResult(http.StatusBadRequest, place, nil, err))
+		return
+	}
+
+	err := repeater.db.CreateProject(project)
+	if err != nil {
+		SendResult(rw, NewResult(http.StatusInternalServerError, place, nil, err))
+	} else {
+		SendResult(rw, NewResult(http.StatusCreated, place, project, err))
+	}
+}
+
+func (repeater *Repeater) GetProject(rw http.ResponseWriter, r *http.Request) {
+	const place = "GetProject"
+
+	project, ok := repeater.projectOf(rw, r, place)
+	if ok {
+		SendResult(rw, NewResult(http.StatusOK, place, project, nil))
+	}
+}
+
+// UpdateProject rename or archive project. Fields missing in
+// json body keep their values. Active project can not be archived
+func (repeater *Repeater) UpdateProject(rw http.ResponseWriter, r *http.Request) {
+	const place = "UpdateProject"
+
+	project, ok := repeater.projectOf(rw, r, place)
+	if !ok {
+		return
+	}
+	id, active := project.ID, project.Active
+	if err := projectFromBody(r, project); err != nil {
+		SendResult(rw, NewResult(http.StatusBadRequest, place, nil, err))
+		return
+	}
+	project.ID, project.Active = id, active
+	if project.Active && project.Archived {
+		SendResult(rw, NewResult(http.StatusConflict, place, nil, errActiveProject))
+		return
+	}
+
+	if err := repeater.db.UpdateProject(project); err != nil {
+		sendNotFoundOr(rw, place, err, errNoProject.Error())
+		return
+	}
+	SendResult(rw, NewResult(http.StatusOK, place, project, nil))
+}
+
+// ActivateProject make proxy record traffic to project
+func (repeater *Repeater) ActivateProject(rw http.ResponseWriter, r *http.Request) {
+	const place = "ActivateProject"
+
+	project, ok := repeater.projectOf(rw, r, place)
+	if !ok {
+		return
+	}
+	if project.Archived {
+		SendResult(rw, NewResult(http.StatusConflict, place, nil, errArchivedProject))
+		return
+	}
+
+	if err := repeater.db.ActivateProject(int32(project.ID)); err != nil {
+		sendNotFoundOr(rw, place, err, errNoProject.Error())
+		return
+	}
+	project.Active = true
+	SendResult(rw, NewResult(http.StatusOK, place, project, nil))
+}
+
+// DeleteProject remove project with all its traffic, rules and
+// intercepted messages. Active and default projects can not be deleted
+func (repeater *Repeater) DeleteProject(rw http.ResponseWriter, r *http.Request) {
+	const place = "DeleteProject"
+
+	project, ok := repeater.projectOf(rw, r, place)
+	if !ok {
+		return
+	}
+	if project.Active {
+		SendResult(rw, NewResult(http.StatusConflict, place, nil, errActiveProject))
+		return
+	}
+
+	err := repeater.db.DeleteProject(int32(project.ID))
+	if err == database.ErrDefaultProject {
+		SendResult(rw, NewResult(http.StatusConflict, place, nil, err))
+		return
+	}
+	if err != nil {
+		sendNotFoundOr(rw, place, err, errNoProject.Error())
+		return
+	}
+	SendResult(rw, NewResult(http.StatusOK, place, nil, nil))
+}
+
+// projectFromBody decode name and archived flag from json body
+// over project
+func projectFromBody(r *http.Request, project *models.ProjectDB) error {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(body, project); err != nil {
+		return err
+	}
+	project.Name = strings.TrimSpace(project.Name)
+	if project.Name == "" {
+		return errors.New("name of project is required")
+	}
+	return nil
+}
+
+// projectOf return project, which id is in path or in project
+// parameter, or the active project if id is not set. If there is
+// no such project, the error is sent and false is returned
+func (repeater *Repeater) projectOf(rw http.ResponseWriter, r *http.Request, place string) (*models.ProjectDB, bool) {
+	var (
+		project *models.ProjectDB
+		id      int
+		err     error
+	)
+	if _, ok := mux.Vars(r)["project"]; ok {
+		var pathID int32
+		pathID, err = IDFromPath(r, "project")
+		id = int(pathID)
+	} else {
+		id, err = idParameter(r.URL.Query(), "project")
+	}
+	if err != nil {
+		SendResult(rw, NewResult(http.StatusBadRequest, place, nil, err))
+		return nil, false
+	}
+
+	if id == 0 {
+		project, err = repeater.db.GetActiveProject()
+	} else {
+		project, err = repeater.db.GetProject(int32(id))
+	}
+	if err != nil {
+		sendNotFoundOr(rw, place, err, errNoProject.Error())
+		return nil, false
+	}
+	return project, true
+}
+
+// openProject return project as projectOf, if traffic can be added
+// to it. Archived project is read only
+func (repeater *Repeater) openProject(rw http.ResponseWriter, r *http.Request, place string) (*models.ProjectDB, bool) {
+	project, ok := repeater.projectOf(rw, r, place)
+	if ok && project.Archived {
+		SendResult(rw, NewResult(http.StatusConflict, place, nil, errArchivedProject))
+		return nil, false
+	}
+	return project, ok
+}
+
+// requestOf return request with id from path, if it belongs to
+// project of r, with the project. Requests of other projects
+// are not found
+func (repeater *Repeater) requestOf(rw http.ResponseWriter, r *http.Request, place string) (*models.RequestDB, *models.ProjectDB, bool) {
+	id, err := IDFromPath(r, "id")
+	if err != nil {
+		SendResult(rw, NewResult(http.StatusBadRequest, place, nil, err))
+		return nil, nil, false
+	}
+	project, ok := repeater.projectOf(rw, r, place)
+	if !ok {
+		return nil, nil, false
+	}
+
+	request, err := repeater.db.GetRequest(id)
+	if err == nil && request.ProjectID != project.ID {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		sendNotFoundOr(rw, place, err, "no such request")
+		return nil, nil, false
+	}
+	return request, project, true
+}
+
+// historyQuery make query of history of project of r
+// from parameters of r
+func (repeater *Repeater) historyQuery(rw http.ResponseWriter, r *http.Request, place string) (models.HistoryQuery, *models.ProjectDB, bool) {
+	query, err := historyQueryFromURL(r)
+	if err != nil {
+		SendResult(rw, NewResult(http.StatusBadRequest, place, nil, err))
+		return query, nil, false
+	}
+	project, ok := repeater.projectOf(rw, r, place)
+	if ok {
+		query.Project = project.ID
+	}
+	return query, project, ok
+}
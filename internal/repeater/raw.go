@@ -0,0 +1,92 @@
+package repeater
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/proxy"
+)
+
+const (
+	// rawTimeout - default time to wait for the answer to raw request
+	rawTimeout = 10 * time.Second
+	// maxRawTimeout - the longest time to wait for the answer to raw
+	// request. Write deadline of server is lifted for it
+	maxRawTimeout = 5 * time.Minute
+)
+
+// SendRaw send the body of request to the server byte for byte and
+// write back the raw answer. The server is set in query: host, port,
+// tls, insecure (skip certificate check) and timeout in seconds.
+// Answer longer than 10 MB is cut off
+func (repeater *Repeater) SendRaw(rw http.ResponseWriter, r *http.Request) {
+	const place = "SendRaw"
+
+	target, err := rawTargetFromQuery(r)
+	if err != nil {
+		SendResult(rw, NewResult(http.StatusBadRequest, place, nil, err))
+		return
+	}
+	raw, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		SendResult(rw, NewResult(http.StatusBadRequest, place, nil, err))
+		return
+	}
+	if len(raw) == 0 {
+		SendResult(rw, NewResult(http.StatusBadRequest, place, nil, errors.New("raw request is empty")))
+		return
+	}
+
+	liftWriteDeadline(rw, place)
+	answer, err := proxy.SendRaw(target, raw)
+	if err != nil {
+		SendResult(rw, NewResult(http.StatusServiceUnavailable, place, nil, err))
+		return
+	}
+	rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	rw.Write(answer)
+}
+
+func rawTargetFromQuery(r *http.Request) (proxy.RawTarget, error) {
+	var (
+		query  = r.URL.Query()
+		target = proxy.RawTarget{
+			Host:    query.Get("host"),
+			Port:    query.Get("port"),
+			Timeout: rawTimeout,
+		}
+		err error
+	)
+	if target.Host == "" {
+		return target, errors.New("host is required")
+	}
+	if value := query.Get("tls"); value != "" {
+		if target.TLS, err = strconv.ParseBool(value); err != nil {
+			return target, errors.New("tls must be true or false")
+		}
+	}
+	if value := query.Get("insecure"); value != "" {
+		if target.Insecure, err = strconv.ParseBool(value); err != nil {
+			return target, errors.New("insecure must be true or false")
+		}
+	}
+	if value := query.Get("timeout"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds <= 0 || seconds > int(maxRawTimeout/time.Second) {
+			return target, errors.New("timeout must be from 1 to " +
+				strconv.Itoa(int(maxRawTimeout/time.Second)) + " seconds")
+		}
+		target.Timeout = time.Duration(seconds) * time.Second
+	}
+	if target.Port == "" {
+		if target.TLS {
+			target.Port = "443"
+		} else {
+			target.Port = "80"
+		}
+	}
+	return target, nil
+}
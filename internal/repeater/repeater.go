@@ -1,10 +1,13 @@
 package repeater
 
 import (
+	"bytes"
 	"crypto/tls"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"strconv"
@@ -21,7 +24,7 @@ import (
 type Repeater struct {
 	server *http.Server
 	proxy  *proxy.Proxy
-	db     *database.DB
+	db     database.Store
 }
 
 func Init() (*Repeater, error) {
@@ -61,7 +64,6 @@ func Init() (*Repeater, error) {
 		IdleTimeout:    idleTimeout,
 		Handler:        repeater.router(),
 		MaxHeaderBytes: maxHeaderBytes,
-		TLSNextProto:   make(map[string]func(*http.Server, *tls.Conn, http.Handler)),
 		TLSConfig:      config,
 	}
 	return repeater, nil
@@ -76,8 +78,42 @@ func (repeater *Repeater) router() *mux.Router {
 	})
 	r.HandleFunc("/history", repeater.GetRequests).Methods("GET")
 	r.HandleFunc("/history", repeater.DeleteRequests).Methods("DELETE")
+	r.HandleFunc("/history/export.har", repeater.ExportHAR).Methods("GET")
+	r.HandleFunc("/history/export.postman", repeater.ExportPostman).Methods("GET")
+	r.HandleFunc("/history/import", repeater.ImportHAR).Methods("POST")
+	r.HandleFunc("/history/import/curl", repeater.ImportCurl).Methods("POST")
+	r.HandleFunc("/history/import/postman", repeater.ImportPostman).Methods("POST")
 	r.HandleFunc("/history/{id}", repeater.GetRequest).Methods("GET")
 	r.HandleFunc("/history/{id}/send", repeater.SendRequest)
+	r.HandleFunc("/history/{id}/tags", repeater.SetTags).Methods("PUT")
+	r.HandleFunc("/history/{id}/body", repeater.GetBody).Methods("GET")
+	r.HandleFunc("/history/{id}/export", repeater.ExportRequest).Methods("GET")
+	r.HandleFunc("/history/{id}/websocket", repeater.GetWebSocketMessages).Methods("GET")
+	r.HandleFunc("/search", repeater.Search).Methods("GET")
+	r.HandleFunc("/send/raw", repeater.SendRaw).Methods("POST")
+
+	r.HandleFunc("/intercept", repeater.GetInterceptSettings).Methods("GET")
+	r.HandleFunc("/intercept", repeater.UpdateInterceptSettings).Methods("PUT")
+	r.HandleFunc("/intercept/queue", repeater.GetInterceptQueue).Methods("GET")
+	r.HandleFunc("/intercept/{id}/forward", repeater.ForwardIntercepted).Methods("POST")
+	r.HandleFunc("/intercept/{id}/drop", repeater.DropIntercepted).Methods("POST")
+
+	r.HandleFunc("/rules", repeater.GetRules).Methods("GET")
+	r.HandleFunc("/rules", repeater.CreateRule).Methods("POST")
+	r.HandleFunc("/rules/{id}", repeater.GetRule).Methods("GET")
+	r.HandleFunc("/rules/{id}", repeater.UpdateRule).Methods("PUT")
+	r.HandleFunc("/rules/{id}", repeater.DeleteRule).Methods("DELETE")
+
+	r.HandleFunc("/projects", repeater.GetProjects).Methods("GET")
+	r.HandleFunc("/projects", repeater.CreateProject).Methods("POST")
+	r.HandleFunc("/projects/import", repeater.ImportArchive).Methods("POST")
+	r.HandleFunc("/projects/{project}", repeater.GetProject).Methods("GET")
+	r.HandleFunc("/projects/{project}", repeater.UpdateProject).Methods("PUT")
+	r.HandleFunc("/projects/{project}", repeater.DeleteProject).Methods("DELETE")
+	r.HandleFunc("/projects/{project}/activate", repeater.ActivateProject).Methods("POST")
+	r.HandleFunc("/projects/{project}/export.har", repeater.ExportHAR).Methods("GET")
+	r.HandleFunc("/projects/{project}/export.postman", repeater.ExportPostman).Methods("GET")
+	r.HandleFunc("/projects/{project}/export.archive", repeater.ExportArchive).Methods("GET")
 
 	return r
 }
@@ -87,14 +123,25 @@ func (repeater *Repeater) Run() {
 	repeater.server.ListenAndServe()
 }
 
+// Proxy return proxy, that sends requests of repeater
+func (repeater *Repeater) Proxy() *proxy.Proxy {
+	return repeater.proxy
+}
+
 func (repeater *Repeater) Close() {
 	repeater.db.Close()
 }
 
+// DeleteRequests remove history of the project, set by project
+// parameter, or of the active one
 func (repeater *Repeater) DeleteRequests(rw http.ResponseWriter, r *http.Request) {
 	const place = "DeleteRequests"
 
-	err := repeater.db.DeleteRequests()
+	project, ok := repeater.projectOf(rw, r, place)
+	if !ok {
+		return
+	}
+	err := repeater.db.DeleteRequests(project.ID)
 	if err != nil {
 		SendResult(rw, NewResult(http.StatusInternalServerError, place, nil, err))
 	} else {
@@ -105,13 +152,16 @@ func (repeater *Repeater) DeleteRequests(rw http.ResponseWriter, r *http.Request
 func (repeater *Repeater) GetRequests(rw http.ResponseWriter, r *http.Request) {
 	const place = "GetRequests"
 
-	scheme := r.URL.Query().Get("scheme")
-	method := r.URL.Query().Get("method")
-	limit := r.URL.Query().Get("limit")
-	last := r.URL.Query().Get("last")
-	host := r.URL.Query().Get("host")
+	query, _, ok := repeater.historyQuery(rw, r, place)
+	if !ok {
+		return
+	}
+	if query.Limit > maxLimit {
+		SendResult(rw, NewResult(http.StatusBadRequest, place, nil, errLimit))
+		return
+	}
 
-	requests, err := repeater.db.GetRequests(scheme, method, limit, last, host)
+	requests, err := repeater.db.GetRequests(query)
 	if err != nil {
 		SendResult(rw, NewResult(http.StatusInternalServerError, place, nil, err))
 	} else {
@@ -122,48 +172,111 @@ func (repeater *Repeater) GetRequests(rw http.ResponseWriter, r *http.Request) {
 func (repeater *Repeater) GetRequest(rw http.ResponseWriter, r *http.Request) {
 	const place = "GetRequest"
 
-	id, err := IDFromPath(r, "id")
-	if err != nil {
+	request, _, ok := repeater.requestOf(rw, r, place)
+	if !ok {
+		return
+	}
+	response, err := repeater.db.GetResponse(int32(request.ID))
+	if err == nil {
+		request.Response = response
+	} else if err != sql.ErrNoRows {
 		SendResult(rw, NewResult(http.StatusInternalServerError, place, nil, err))
+		return
+	}
+	SendResult(rw, NewResult(http.StatusOK, place, request, nil))
+}
+
+func (repeater *Repeater) GetWebSocketMessages(rw http.ResponseWriter, r *http.Request) {
+	const place = "GetWebSocketMessages"
+
+	request, _, ok := repeater.requestOf(rw, r, place)
+	if !ok {
+		return
 	}
 
-	request, err := repeater.db.GetRequest(id)
+	messages, err := repeater.db.GetWebSocketMessages(int32(request.ID))
 	if err != nil {
 		SendResult(rw, NewResult(http.StatusInternalServerError, place, nil, err))
 	} else {
-		SendResult(rw, NewResult(http.StatusOK, place, request, err))
+		SendResult(rw, NewResult(http.StatusOK, place, messages, err))
 	}
 }
 
+// SendRequest send the stored request again. The body can have
+// json RequestPatch with changes to apply before sending. The sent
+// request is saved as a new history entry with the stored one as parent
+// to the project of the stored one
 func (repeater *Repeater) SendRequest(rw http.ResponseWriter, r *http.Request) {
-	const place = "GetRequest"
+	const place = "SendRequest"
 
-	id, err := IDFromPath(r, "id")
+	request, project, ok := repeater.requestOf(rw, r, place)
+	if !ok {
+		return
+	}
+	if project.Archived {
+		SendResult(rw, NewResult(http.StatusConflict, place, nil, errArchivedProject))
+		return
+	}
+
+	patch, err := patchFromBody(r)
+	if err == nil {
+		err = patch.Apply(request)
+	}
 	if err != nil {
-		SendResult(rw, NewResult(http.StatusInternalServerError, place, nil, err))
+		SendResult(rw, NewResult(http.StatusBadRequest, place, nil, err))
+		return
 	}
+	if request.Truncated {
+		SendResult(rw, NewResult(http.StatusBadRequest, place, nil,
+			errors.New("body of the request was saved truncated, send it with the whole body in patch")))
+		return
+	}
+	parent := request.ID
+	request.ID = 0
+	request.Parent = &parent
+	request.Add = time.Time{}
+
+	if err = repeater.Do(rw, *request); err != nil {
+		SendResult(rw, NewResult(http.StatusServiceUnavailable, place, nil, err))
+	}
+}
 
-	request, err := repeater.db.GetRequest(id)
-	request.MakeHeader()
-	repeater.Do(rw, *request)
+// patchFromBody decode RequestPatch from json body. Empty body
+// is empty patch
+func patchFromBody(r *http.Request) (*models.RequestPatch, error) {
+	var patch = &models.RequestPatch{}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil || len(strings.TrimSpace(string(body))) == 0 {
+		return patch, err
+	}
+	return patch, json.Unmarshal(body, patch)
 }
 
+// Do send rdb again. HTTP/1.x requests are sent byte for byte with
+// the original order and casing of header lines, h2 ones through client
 func (repeater *Repeater) Do(w http.ResponseWriter, rdb models.RequestDB) error {
+	if rdb.Proto != "HTTP/2.0" {
+		return repeater.proxy.DoRaw(w, &rdb)
+	}
 	req, err := restoreRequest(rdb)
 	if err != nil {
 		return err
 	}
-	return repeater.proxy.Do(w, req)
+	return repeater.proxy.Do(w, req, &rdb)
 }
 
 func restoreRequest(rdb models.RequestDB) (*http.Request, error) {
-	body := strings.NewReader(string(rdb.Body))
-	req, err := http.NewRequest(rdb.Method, rdb.Scheme+"://"+rdb.RemoteAddr, body)
+	body := bytes.NewReader(rdb.Body)
+	req, err := http.NewRequest(rdb.Method, rdb.TargetURL(), body)
 	if err != nil {
 		return req, err
 	}
-	for k, v := range rdb.Header {
-		req.Header.Set(k, v)
+	for _, field := range rdb.Header {
+		if strings.EqualFold(field.Name, "Host") {
+			req.Host = field.Value
+			continue
+		}
+		req.Header.Add(field.Name, field.Value)
 	}
 
 	if rdb.UserLogin != "" && rdb.UserPassword != "" {
@@ -187,28 +300,29 @@ func SendResult(rw http.ResponseWriter, result models.Result) {
 	}
 
 	if result.Err != nil {
-		sendErrorJSON(rw, result.Err, result.Place)
+		sendErrorJSON(rw, result.Code, result.Err, result.Place)
 	} else {
-		sendSuccessJSON(rw, result.Send, result.Place)
+		sendSuccessJSON(rw, result.Code, result.Send, result.Place)
 	}
-	rw.WriteHeader(result.Code)
 }
 
-// SendErrorJSON send error json
-func sendErrorJSON(rw http.ResponseWriter, catched error, place string) {
+// SendErrorJSON send error json with status code. The status
+// must be written before the body, otherwise it is 200
+func sendErrorJSON(rw http.ResponseWriter, code int, catched error, place string) {
 	result := &models.ResultModel{
 		Place:   place,
 		Success: false,
 		Message: catched.Error(),
 	}
 
+	rw.WriteHeader(code)
 	if b, err := json.Marshal(result); err == nil {
 		rw.Write(b)
 	}
 }
 
-// SendSuccessJSON send object json
-func sendSuccessJSON(rw http.ResponseWriter, result interface{}, place string) {
+// SendSuccessJSON send object json with status code
+func sendSuccessJSON(rw http.ResponseWriter, code int, result interface{}, place string) {
 	if result == nil {
 		result = &models.ResultModel{
 			Place:   place,
@@ -217,6 +331,7 @@ func sendSuccessJSON(rw http.ResponseWriter, result interface{}, place string) {
 		}
 	}
 
+	rw.WriteHeader(code)
 	if b, err := json.Marshal(result); err == nil {
 		rw.Write(b)
 	}
@@ -0,0 +1,234 @@
+package repeater
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/database"
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+// testRepeater return repeater with empty memory store
+func testRepeater(t *testing.T) (*Repeater, database.Store) {
+	t.Helper()
+	db, err := database.NewMemory(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return &Repeater{db: db}, db
+}
+
+// serve send request to repeater and decode json answer to value,
+// if it is set. The status of answer is returned
+func serve(t *testing.T, repeater *Repeater, method, target, body string, value interface{}) int {
+	t.Helper()
+	var (
+		rw = httptest.NewRecorder()
+		r  = httptest.NewRequest(method, target, strings.NewReader(body))
+	)
+	repeater.router().ServeHTTP(rw, r)
+	if value != nil && rw.Code < 300 {
+		if err := json.Unmarshal(rw.Body.Bytes(), value); err != nil {
+			t.Fatalf("%s %s answered %s: %v", method, target, rw.Body, err)
+		}
+	}
+	return rw.Code
+}
+
+// createProject add not active project
+func createProject(t *testing.T, db database.Store, name string) int {
+	t.Helper()
+	project := &models.ProjectDB{Name: name}
+	if err := db.CreateProject(project); err != nil {
+		t.Fatal(err)
+	}
+	return project.ID
+}
+
+// createRequest add request with response to project
+func createRequest(t *testing.T, db database.Store, project int, path, body string) int {
+	t.Helper()
+	request := &models.RequestDB{
+		Method:     "GET",
+		Scheme:     "http",
+		RemoteAddr: "example.com",
+		Path:       path,
+		ProjectID:  project,
+	}
+	request.URL = request.TargetURL()
+	if err := db.CreateRequest(request); err != nil {
+		t.Fatal(err)
+	}
+	response := &models.ResponseDB{RequestID: request.ID, Status: http.StatusOK, Body: []byte(body)}
+	if err := db.CreateResponse(response); err != nil {
+		t.Fatal(err)
+	}
+	return request.ID
+}
+
+func requestIDs(requests []models.RequestDB) []int {
+	ids := make([]int, 0, len(requests))
+	for _, request := range requests {
+		ids = append(ids, request.ID)
+	}
+	return ids
+}
+
+func TestGetRequests(t *testing.T) {
+	repeater, db := testRepeater(t)
+	other := createProject(t, db, "other")
+	for i := 1; i <= 3; i++ {
+		createRequest(t, db, models.DefaultProject, "/"+strconv.Itoa(i), "")
+	}
+	createRequest(t, db, other, "/other", "")
+
+	tests := []struct {
+		target string
+		status int
+		want   []int
+	}{
+		{"/history", http.StatusOK, []int{3, 2, 1}},
+		{"/history?limit=2&last=false", http.StatusOK, []int{1, 2}},
+		{"/history?limit=1000", http.StatusOK, []int{3, 2, 1}},
+		{"/history?path=/2", http.StatusOK, []int{2}},
+		{"/history?project=" + strconv.Itoa(other), http.StatusOK, []int{4}},
+		{"/history?limit=0", http.StatusBadRequest, nil},
+		{"/history?limit=1001", http.StatusBadRequest, nil},
+		{"/history?limit=many", http.StatusBadRequest, nil},
+		{"/history?filter={", http.StatusBadRequest, nil},
+		{"/history?project=9", http.StatusNotFound, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.target, func(t *testing.T) {
+			var requests models.RequestsDB
+			if status := serve(t, repeater, "GET", test.target, "", &requests); status != test.status {
+				t.Fatalf("got status %d, want %d", status, test.status)
+			}
+			if test.want == nil {
+				return
+			}
+			if got := requestIDs(requests.Requests); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got requests %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	repeater, db := testRepeater(t)
+	createRequest(t, db, models.DefaultProject, "/a", "no")
+	found := createRequest(t, db, models.DefaultProject, "/b", "secret token")
+	createRequest(t, db, createProject(t, db, "other"), "/c", "secret token")
+
+	var results models.SearchResults
+	if status := serve(t, repeater, "GET", "/search?q=token", "", &results); status != http.StatusOK {
+		t.Fatalf("got status %d", status)
+	}
+	if len(results.Results) != 1 || results.Total != 1 {
+		t.Fatalf("got %d results of %d, want one", len(results.Results), results.Total)
+	}
+	result := results.Results[0]
+	if result.Request.ID != found || len(result.Matches) != 1 ||
+		result.Matches[0].Part != models.SearchResponseBody || result.Request.Response != nil {
+		t.Errorf("got result %+v", result)
+	}
+
+	if status := serve(t, repeater, "GET", "/search", "", nil); status != http.StatusBadRequest {
+		t.Errorf("search without text answered %d", status)
+	}
+	if status := serve(t, repeater, "GET", "/search?q=token&limit=5000", "", nil); status != http.StatusBadRequest {
+		t.Errorf("search with too big limit answered %d", status)
+	}
+}
+
+func TestProjects(t *testing.T) {
+	repeater, _ := testRepeater(t)
+	var project models.ProjectDB
+	if status := serve(t, repeater, "POST", "/projects", `{"name": " engagement "}`, &project); status != http.StatusCreated {
+		t.Fatalf("project is created with status %d", status)
+	}
+	if project.Name != "engagement" || project.Active {
+		t.Errorf("created project %+v", project)
+	}
+	id := strconv.Itoa(project.ID)
+
+	steps := []struct {
+		method string
+		target string
+		body   string
+		status int
+	}{
+		{"POST", "/projects", `{"name": ""}`, http.StatusBadRequest},
+		{"POST", "/projects/" + id + "/activate", "", http.StatusOK},
+		{"DELETE", "/projects/" + id, "", http.StatusConflict},
+		{"PUT", "/projects/" + id, `{"archived": true}`, http.StatusConflict},
+		{"DELETE", "/projects/1", "", http.StatusConflict},
+		{"POST", "/projects/1/activate", "", http.StatusOK},
+		{"PUT", "/projects/" + id, `{"archived": true}`, http.StatusOK},
+		{"POST", "/projects/" + id + "/activate", "", http.StatusConflict},
+		{"POST", "/history/import/curl?project=" + id, "curl http://example.com", http.StatusConflict},
+		{"DELETE", "/projects/" + id, "", http.StatusOK},
+		{"GET", "/projects/" + id, "", http.StatusNotFound},
+		{"DELETE", "/projects/" + id, "", http.StatusNotFound},
+	}
+	for _, step := range steps {
+		if status := serve(t, repeater, step.method, step.target, step.body, nil); status != step.status {
+			t.Errorf("%s %s answered %d, want %d", step.method, step.target, status, step.status)
+		}
+	}
+}
+
+func TestProjectScope(t *testing.T) {
+	repeater, db := testRepeater(t)
+	other := createProject(t, db, "other")
+	project := "?project=" + strconv.Itoa(other)
+	request := strconv.Itoa(createRequest(t, db, other, "/", "hello"))
+
+	var rule models.RuleDB
+	body := `{"enabled": true, "target": "request_header", "replace": "X-Test: 1"}`
+	if status := serve(t, repeater, "POST", "/rules"+project, body, &rule); status != http.StatusCreated {
+		t.Fatalf("rule is created with status %d", status)
+	}
+	item := &models.InterceptItem{ProjectID: other, Kind: models.InterceptRequest}
+	if err := db.CreateInterceptItem(item); err != nil {
+		t.Fatal(err)
+	}
+	ruleID, itemID := strconv.Itoa(rule.ID), strconv.Itoa(item.ID)
+
+	tests := []struct {
+		method string
+		target string
+		status int
+	}{
+		{"GET", "/history/" + request, http.StatusNotFound},
+		{"GET", "/history/" + request + project, http.StatusOK},
+		{"GET", "/history/" + request + "/body", http.StatusNotFound},
+		{"GET", "/history/" + request + "/body" + project, http.StatusOK},
+		{"GET", "/rules/" + ruleID, http.StatusNotFound},
+		{"GET", "/rules/" + ruleID + project, http.StatusOK},
+		{"POST", "/intercept/" + itemID + "/forward", http.StatusNotFound},
+		{"POST", "/intercept/" + itemID + "/drop" + project, http.StatusOK},
+		{"POST", "/intercept/" + itemID + "/drop" + project, http.StatusNotFound},
+	}
+	for _, test := range tests {
+		if status := serve(t, repeater, test.method, test.target, "", nil); status != test.status {
+			t.Errorf("%s %s answered %d, want %d", test.method, test.target, status, test.status)
+		}
+	}
+
+	var rules models.RulesDB
+	serve(t, repeater, "GET", "/rules", "", &rules)
+	if len(rules.Rules) > 0 {
+		t.Errorf("rules of other project are got for the active one: %+v", rules.Rules)
+	}
+	serve(t, repeater, "GET", "/rules"+project, "", &rules)
+	if len(rules.Rules) != 1 || rules.Rules[0].ProjectID != other {
+		t.Errorf("got rules %+v of other project", rules.Rules)
+	}
+}
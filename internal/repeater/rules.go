@@ -0,0 +1,136 @@
+package repeater
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+var errNoRule = errors.New("no such rule")
+
+// GetRules return rules of project from parameter or of the active one
+func (repeater *Repeater) GetRules(rw http.ResponseWriter, r *http.Request) {
+	const place = "GetRules"
+
+	project, ok := repeater.projectOf(rw, r, place)
+	if !ok {
+		return
+	}
+
+	rules, err := repeater.db.GetRules(project.ID)
+	if err != nil {
+		SendResult(rw, NewResult(http.StatusInternalServerError, place, nil, err))
+	} else {
+		SendResult(rw, NewResult(http.StatusOK, place, rules, err))
+	}
+}
+
+func (repeater *Repeater) CreateRule(rw http.ResponseWriter, r *http.Request) {
+	const place = "CreateRule"
+
+	project, ok := repeater.projectOf(rw, r, place)
+	if !ok {
+		return
+	}
+	rule, err := ruleFromBody(r)
+	if err != nil {
+		SendResult(rw, NewResult(http.StatusBadRequest, place, nil, err))
+		return
+	}
+	rule.ProjectID = project.ID
+
+	err = repeater.db.CreateRule(rule)
+	if err != nil {
+		SendResult(rw, NewResult(http.StatusInternalServerError, place, nil, err))
+	} else {
+		SendResult(rw, NewResult(http.StatusCreated, place, rule, err))
+	}
+}
+
+func (repeater *Repeater) GetRule(rw http.ResponseWriter, r *http.Request) {
+	const place = "GetRule"
+
+	rule, ok := repeater.ruleOf(rw, r, place)
+	if ok {
+		SendResult(rw, NewResult(http.StatusOK, place, rule, nil))
+	}
+}
+
+func (repeater *Repeater) UpdateRule(rw http.ResponseWriter, r *http.Request) {
+	const place = "UpdateRule"
+
+	old, ok := repeater.ruleOf(rw, r, place)
+	if !ok {
+		return
+	}
+	rule, err := ruleFromBody(r)
+	if err != nil {
+		SendResult(rw, NewResult(http.StatusBadRequest, place, nil, err))
+		return
+	}
+	rule.ID, rule.ProjectID = old.ID, old.ProjectID
+
+	err = repeater.db.UpdateRule(rule)
+	if err == sql.ErrNoRows {
+		SendResult(rw, NewResult(http.StatusNotFound, place, nil, errNoRule))
+	} else if err != nil {
+		SendResult(rw, NewResult(http.StatusInternalServerError, place, nil, err))
+	} else {
+		SendResult(rw, NewResult(http.StatusOK, place, rule, err))
+	}
+}
+
+func (repeater *Repeater) DeleteRule(rw http.ResponseWriter, r *http.Request) {
+	const place = "DeleteRule"
+
+	rule, ok := repeater.ruleOf(rw, r, place)
+	if !ok {
+		return
+	}
+
+	err := repeater.db.DeleteRule(int32(rule.ID))
+	if err == sql.ErrNoRows {
+		SendResult(rw, NewResult(http.StatusNotFound, place, nil, errNoRule))
+	} else if err != nil {
+		SendResult(rw, NewResult(http.StatusInternalServerError, place, nil, err))
+	} else {
+		SendResult(rw, NewResult(http.StatusOK, place, nil, err))
+	}
+}
+
+// ruleOf return rule with id from path, if it belongs to project
+// of r. Rules of other projects are not found
+func (repeater *Repeater) ruleOf(rw http.ResponseWriter, r *http.Request, place string) (*models.RuleDB, bool) {
+	id, err := IDFromPath(r, "id")
+	if err != nil {
+		SendResult(rw, NewResult(http.StatusBadRequest, place, nil, err))
+		return nil, false
+	}
+	project, ok := repeater.projectOf(rw, r, place)
+	if !ok {
+		return nil, false
+	}
+
+	rule, err := repeater.db.GetRule(id)
+	if err == nil && rule.ProjectID != project.ID {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		sendNotFoundOr(rw, place, err, errNoRule.Error())
+		return nil, false
+	}
+	return rule, true
+}
+
+// ruleFromBody decode rule from json body and check it. Rules
+// are enabled unless the body says otherwise
+func ruleFromBody(r *http.Request) (*models.RuleDB, error) {
+	var rule = &models.RuleDB{Enabled: true}
+	if err := json.NewDecoder(r.Body).Decode(rule); err != nil {
+		return nil, err
+	}
+	return rule, rule.Compile()
+}
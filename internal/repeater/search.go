@@ -0,0 +1,63 @@
+package repeater
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+// Search find requests, which url, headers or text bodies of request
+// or response have text from q parameter. Other parameters are the
+// same as for history. Every result has snippets around found text
+func (repeater *Repeater) Search(rw http.ResponseWriter, r *http.Request) {
+	const place = "Search"
+
+	text := strings.TrimSpace(r.URL.Query().Get("q"))
+	if text == "" {
+		SendResult(rw, NewResult(http.StatusBadRequest, place, nil, errors.New("q is required")))
+		return
+	}
+	query, _, ok := repeater.historyQuery(rw, r, place)
+	if !ok {
+		return
+	}
+	if query.Limit > maxLimit {
+		SendResult(rw, NewResult(http.StatusBadRequest, place, nil, errLimit))
+		return
+	}
+	query.Filter.Text = text
+
+	requests, err := repeater.db.GetRequests(query)
+	if err != nil {
+		SendResult(rw, NewResult(http.StatusInternalServerError, place, nil, err))
+		return
+	}
+
+	var results = &models.SearchResults{
+		Results: make([]models.SearchResult, 0, len(requests.Requests)),
+		Next:    requests.Next,
+		Total:   requests.Total,
+	}
+	ids := make([]int, 0, len(requests.Requests))
+	for _, request := range requests.Requests {
+		ids = append(ids, request.ID)
+	}
+	responses, err := repeater.db.GetResponses(ids)
+	if err != nil {
+		SendResult(rw, NewResult(http.StatusInternalServerError, place, nil, err))
+		return
+	}
+	for _, request := range requests.Requests {
+		request.Response = responses[request.ID]
+		matches := request.Matches(text)
+		request.Body = nil
+		request.Response = nil
+		results.Results = append(results.Results, models.SearchResult{
+			Request: request,
+			Matches: matches,
+		})
+	}
+	SendResult(rw, NewResult(http.StatusOK, place, results, nil))
+}
@@ -0,0 +1,58 @@
+package repeater
+
+import (
+	"io/ioutil"
+	"net/http"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/snippet"
+)
+
+// ExportRequest send the stored request as command or code, that
+// sends it. Query: format - curl (default), wget, httpie,
+// python-requests, go or powershell
+func (repeater *Repeater) ExportRequest(rw http.ResponseWriter, r *http.Request) {
+	const place = "ExportRequest"
+
+	request, _, ok := repeater.requestOf(rw, r, place)
+	if !ok {
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = snippet.Curl
+	}
+	code, err := snippet.Render(format, request)
+	if err != nil {
+		SendResult(rw, NewResult(http.StatusBadRequest, place, nil, err))
+		return
+	}
+	rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	rw.Write([]byte(code))
+}
+
+// ImportCurl save request of curl command line from body to history
+// of the project as ImportHAR
+func (repeater *Repeater) ImportCurl(rw http.ResponseWriter, r *http.Request) {
+	const place = "ImportCurl"
+
+	project, ok := repeater.openProject(rw, r, place)
+	if !ok {
+		return
+	}
+	command, err := ioutil.ReadAll(http.MaxBytesReader(rw, r.Body, maxImportSize))
+	if err != nil {
+		SendResult(rw, NewResult(http.StatusBadRequest, place, nil, err))
+		return
+	}
+	request, err := snippet.ParseCurl(string(command))
+	if err != nil {
+		SendResult(rw, NewResult(http.StatusBadRequest, place, nil, err))
+		return
+	}
+	request.ProjectID = project.ID
+	if err = repeater.db.CreateRequest(request); err != nil {
+		SendResult(rw, NewResult(http.StatusInternalServerError, place, nil, err))
+		return
+	}
+	SendResult(rw, NewResult(http.StatusOK, place, request, nil))
+}
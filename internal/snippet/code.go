@@ -0,0 +1,214 @@
+package snippet
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+func renderPython(r *request) string {
+	var code strings.Builder
+	code.WriteString("import requests\n\n")
+	code.WriteString("url = " + pythonString(r.url) + "\n")
+
+	var args = []string{pythonString(r.method), "url"}
+	if header := mergeHeader(r.header); len(header) > 0 {
+		code.WriteString("headers = {\n")
+		for _, field := range header {
+			code.WriteString("    " + pythonString(field.Name) + ": " + pythonString(field.Value) + ",\n")
+		}
+		code.WriteString("}\n")
+		args = append(args, "headers=headers")
+	}
+	if len(r.body) > 0 {
+		if r.binary() {
+			code.WriteString("data = " + pythonBytes(r.body) + "\n")
+		} else {
+			code.WriteString("data = " + pythonString(string(r.body)) + ".encode('utf-8')\n")
+		}
+		args = append(args, "data=data")
+	}
+	if r.login != "" {
+		args = append(args, "auth=("+pythonString(r.login)+", "+pythonString(r.password)+")")
+	}
+
+	code.WriteString("\nresponse = requests.request(" + strings.Join(args, ", ") + ")\n")
+	code.WriteString("print(response.status_code)\n")
+	code.WriteString("print(response.text)\n")
+	return code.String()
+}
+
+func renderGo(r *request) string {
+	var (
+		code    strings.Builder
+		body    = "nil"
+		imports = []string{"fmt", "io/ioutil", "net/http"}
+	)
+	if len(r.body) > 0 {
+		body = "strings.NewReader(" + strconv.Quote(string(r.body)) + ")"
+		imports = append(imports, "strings")
+	}
+
+	code.WriteString("package main\n\nimport (\n")
+	for _, name := range imports {
+		code.WriteString("\t" + strconv.Quote(name) + "\n")
+	}
+	code.WriteString(")\n\nfunc main() {\n")
+	fmt.Fprintf(&code, "\treq, err := http.NewRequest(%s, %s, %s)\n",
+		strconv.Quote(r.method), strconv.Quote(r.url), body)
+	code.WriteString("\tif err != nil {\n\t\tpanic(err)\n\t}\n")
+	for _, field := range r.header {
+		if strings.EqualFold(field.Name, "Host") {
+			fmt.Fprintf(&code, "\treq.Host = %s\n", strconv.Quote(field.Value))
+			continue
+		}
+		fmt.Fprintf(&code, "\treq.Header.Add(%s, %s)\n", strconv.Quote(field.Name), strconv.Quote(field.Value))
+	}
+	if r.login != "" {
+		fmt.Fprintf(&code, "\treq.SetBasicAuth(%s, %s)\n", strconv.Quote(r.login), strconv.Quote(r.password))
+	}
+	code.WriteString(`
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		panic(err)
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(resp.Status)
+	fmt.Println(string(respBody))
+}
+`)
+	return code.String()
+}
+
+func renderPowerShell(r *request) string {
+	var (
+		code   strings.Builder
+		args   = []string{"Invoke-WebRequest", "-UseBasicParsing", "-Uri " + powerShellString(r.url), "-Method " + powerShellString(r.method)}
+		header = mergeHeader(r.header)
+	)
+	if r.login != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte(r.login + ":" + r.password))
+		header = header.Set("Authorization", "Basic "+auth)
+	}
+
+	var lines []string
+	for _, field := range header {
+		// these headers can be set only with parameters
+		switch strings.ToLower(field.Name) {
+		case "content-type":
+			args = append(args, "-ContentType "+powerShellString(field.Value))
+		case "user-agent":
+			args = append(args, "-UserAgent "+powerShellString(field.Value))
+		default:
+			lines = append(lines, "    "+powerShellString(field.Name)+" = "+powerShellString(field.Value))
+		}
+	}
+	if len(lines) > 0 {
+		code.WriteString("$headers = @{\n" + strings.Join(lines, "\n") + "\n}\n")
+		args = append(args, "-Headers $headers")
+	}
+	if len(r.body) > 0 {
+		if r.binary() {
+			var bytes = make([]string, 0, len(r.body))
+			for _, b := range r.body {
+				bytes = append(bytes, fmt.Sprintf("0x%02x", b))
+			}
+			args = append(args, "-Body ([byte[]]("+strings.Join(bytes, ",")+"))")
+		} else {
+			args = append(args, "-Body "+powerShellString(string(r.body)))
+		}
+	}
+
+	code.WriteString("$response = " + strings.Join(args, " `\n  ") + "\n")
+	code.WriteString("$response.StatusCode\n")
+	code.WriteString("$response.Content\n")
+	return code.String()
+}
+
+// mergeHeader join repeated headers to one line, because dictionaries
+// of python and powershell can not have the same names
+func mergeHeader(header models.Header) models.Header {
+	var (
+		merged = make(models.Header, 0, len(header))
+		index  = make(map[string]int)
+	)
+	for _, field := range header {
+		key := strings.ToLower(field.Name)
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(merged)
+			merged = append(merged, field)
+			continue
+		}
+		separator := ", "
+		if key == "cookie" {
+			separator = "; "
+		}
+		merged[i].Value += separator + field.Value
+	}
+	return merged
+}
+
+// pythonString make python string literal
+func pythonString(s string) string {
+	var quoted strings.Builder
+	quoted.WriteString("'")
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '\'':
+			quoted.WriteString(`\` + string(r))
+		case r == '\n':
+			quoted.WriteString(`\n`)
+		case r == '\r':
+			quoted.WriteString(`\r`)
+		case r == '\t':
+			quoted.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&quoted, `\x%02x`, r)
+		default:
+			quoted.WriteRune(r)
+		}
+	}
+	quoted.WriteString("'")
+	return quoted.String()
+}
+
+// pythonBytes make python bytes literal
+func pythonBytes(b []byte) string {
+	var quoted strings.Builder
+	quoted.WriteString("b'")
+	for _, c := range b {
+		switch {
+		case c == '\\' || c == '\'':
+			quoted.WriteString(`\` + string(c))
+		case c >= 0x20 && c < 0x7f:
+			quoted.WriteByte(c)
+		default:
+			fmt.Fprintf(&quoted, `\x%02x`, c)
+		}
+	}
+	quoted.WriteString("'")
+	return quoted.String()
+}
+
+// powerShellQuotes are doubled in verbatim powershell strings, as
+// powershell ends them with typographic single quotes too
+var powerShellQuotes = strings.NewReplacer(
+	"'", "''",
+	"\u2018", "\u2018\u2018",
+	"\u2019", "\u2019\u2019",
+	"\u201a", "\u201a\u201a",
+	"\u201b", "\u201b\u201b",
+)
+
+// powerShellString make verbatim powershell string
+func powerShellString(s string) string {
+	return "'" + powerShellQuotes.Replace(s) + "'"
+}
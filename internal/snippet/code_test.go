@@ -0,0 +1,28 @@
+package snippet
+
+import "testing"
+
+func TestPowerShellString(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{"empty", "", `''`},
+		{"plain", "abc", `'abc'`},
+		{"single quote", "it's", `'it''s'`},
+		{"dollar is not expanded", "$HOME", `'$HOME'`},
+		{"left quote", "a‘b", "'a‘‘b'"},
+		{"right quote", "it’s", "'it’’s'"},
+		{"low quote", "a‚b", "'a‚‚b'"},
+		{"reversed quote", "a‛b", "'a‛‛b'"},
+		{"mixed quotes", "'’", "'''’’'"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := powerShellString(test.s); got != test.want {
+				t.Errorf("powerShellString(%q) = %s, want %s", test.s, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,321 @@
+package snippet
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+// curlIgnored - options of curl, that do not change the request.
+// The value is true, if the option has argument
+var curlIgnored = map[string]bool{
+	"-s": false, "--silent": false, "-S": false, "--show-error": false,
+	"-v": false, "--verbose": false, "-i": false, "--include": false,
+	"-L": false, "--location": false, "-k": false, "--insecure": false,
+	"-f": false, "--fail": false, "-N": false, "--no-buffer": false,
+	"-#": false, "--progress-bar": false, "-O": false, "--remote-name": false,
+	"-o": true, "--output": true, "-m": true, "--max-time": true,
+	"--connect-timeout": true, "-x": true, "--proxy": true, "-w": true,
+	"--write-out": true, "--retry": true, "--resolve": true, "-c": true,
+	"--cookie-jar": true, "--cacert": true, "-E": true, "--cert": true,
+	"--key": true, "--max-redirs": true, "-r": true, "--range": true,
+}
+
+// curlCommand - parsed options of curl
+type curlCommand struct {
+	method   string
+	target   string
+	header   models.Header
+	data     []string
+	get      bool
+	head     bool
+	proto    string
+	login    string
+	password string
+	// stdin - what is written to curl by printf before it
+	stdin []byte
+}
+
+// ParseCurl make request from curl command line. Body can be passed
+// with printf through pipe, as snippets do. Files are not supported
+func ParseCurl(command string) (*models.RequestDB, error) {
+	commands, err := shellCommands(command)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		curl = &curlCommand{}
+		args []string
+	)
+	for _, words := range commands {
+		if len(words) == 0 {
+			continue
+		}
+		switch words[0] {
+		case "printf":
+			if len(words) < 2 {
+				return nil, errors.New("printf without format")
+			}
+			curl.stdin = printfFormat(words[1])
+		case "curl":
+			args = words[1:]
+		default:
+			return nil, errors.New("unsupported command " + words[0])
+		}
+	}
+	if args == nil {
+		return nil, errors.New("it is not curl command")
+	}
+	if err = curl.parse(args); err != nil {
+		return nil, err
+	}
+	return curl.request()
+}
+
+func (curl *curlCommand) parse(args []string) error {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			curl.target = arg
+			continue
+		}
+
+		name, value, attached := arg, "", false
+		if strings.HasPrefix(arg, "--") {
+			if eq := strings.Index(arg, "="); eq > 0 {
+				name, value, attached = arg[:eq], arg[eq+1:], true
+			}
+		} else if len(arg) > 2 {
+			// several flags like -sSL, the first flag with value takes
+			// the rest of them as value: -XPOST, -sXPOST
+			name = ""
+			for j := 1; j < len(arg); j++ {
+				flag := "-" + arg[j:j+1]
+				if curlTakesValue(flag) {
+					name, value, attached = flag, arg[j+1:], j+1 < len(arg)
+					break
+				}
+				if err := curl.flag(flag); err != nil {
+					return err
+				}
+			}
+			if name == "" {
+				continue
+			}
+		}
+
+		if !curlTakesValue(name) {
+			if attached {
+				return errors.New("option " + name + " has no value")
+			}
+			if err := curl.flag(name); err != nil {
+				return err
+			}
+			continue
+		}
+		if !attached {
+			if i+1 >= len(args) {
+				return errors.New("option " + name + " needs value")
+			}
+			i++
+			value = args[i]
+		}
+		if err := curl.option(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func curlTakesValue(name string) bool {
+	switch name {
+	case "-X", "--request", "-H", "--header", "-d", "--data", "--data-ascii",
+		"--data-binary", "--data-raw", "--data-urlencode", "-u", "--user",
+		"-b", "--cookie", "-A", "--user-agent", "-e", "--referer", "--url":
+		return true
+	}
+	return curlIgnored[name]
+}
+
+// flag apply option without value
+func (curl *curlCommand) flag(name string) error {
+	switch name {
+	case "-G", "--get":
+		curl.get = true
+	case "-I", "--head":
+		curl.head = true
+	case "--compressed":
+		if curl.header.Get("Accept-Encoding") == "" {
+			curl.header = append(curl.header, models.HeaderField{Name: "Accept-Encoding", Value: "deflate, gzip"})
+		}
+	case "--http1.0", "-0":
+		curl.proto = "HTTP/1.0"
+	case "--http1.1":
+		curl.proto = "HTTP/1.1"
+	case "--http2", "--http2-prior-knowledge":
+		curl.proto = "HTTP/2.0"
+	default:
+		if _, ok := curlIgnored[name]; !ok {
+			return errors.New("unsupported option " + name)
+		}
+	}
+	return nil
+}
+
+// option apply option with value
+func (curl *curlCommand) option(name, value string) error {
+	switch name {
+	case "-X", "--request":
+		curl.method = strings.ToUpper(value)
+	case "--url":
+		curl.target = value
+	case "-H", "--header":
+		return curl.addHeader(value)
+	case "-A", "--user-agent":
+		curl.header = curl.header.Set("User-Agent", value)
+	case "-e", "--referer":
+		curl.header = curl.header.Set("Referer", value)
+	case "-b", "--cookie":
+		if !strings.Contains(value, "=") {
+			return errors.New("cookie files are not supported")
+		}
+		curl.header = append(curl.header, models.HeaderField{Name: "Cookie", Value: value})
+	case "-u", "--user":
+		kv := strings.SplitN(value, ":", 2)
+		curl.login = kv[0]
+		if len(kv) == 2 {
+			curl.password = kv[1]
+		}
+	case "-d", "--data", "--data-ascii", "--data-binary", "--data-raw":
+		data, read, err := curl.readData(value, name == "--data-raw")
+		if err != nil {
+			return err
+		}
+		if read && name != "--data-binary" {
+			// curl drops line breaks of data read from file
+			data = strings.NewReplacer("\r", "", "\n", "").Replace(data)
+		}
+		curl.data = append(curl.data, data)
+	case "--data-urlencode":
+		curl.data = append(curl.data, urlencodeData(value))
+	}
+	return nil
+}
+
+// readData return value of data option and if it was read
+// from file. "@-" is stdin
+func (curl *curlCommand) readData(value string, raw bool) (string, bool, error) {
+	if raw || !strings.HasPrefix(value, "@") {
+		return value, false, nil
+	}
+	if value == "@-" && curl.stdin != nil {
+		return string(curl.stdin), true, nil
+	}
+	return "", false, errors.New("files are not supported: " + value)
+}
+
+func (curl *curlCommand) addHeader(line string) error {
+	if strings.HasSuffix(line, ";") && !strings.Contains(line, ":") {
+		// "Name;" is header with empty value
+		curl.header = append(curl.header, models.HeaderField{Name: strings.TrimSuffix(line, ";")})
+		return nil
+	}
+	kv := strings.SplitN(line, ":", 2)
+	if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+		return errors.New("invalid header " + line)
+	}
+	name, value := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+	if value == "" {
+		// "Name:" removes the header curl adds itself
+		curl.header = curl.header.Del(name)
+		return nil
+	}
+	curl.header = append(curl.header, models.HeaderField{Name: name, Value: value})
+	return nil
+}
+
+// urlencodeData encode value of --data-urlencode: "content",
+// "=content" or "name=content"
+func urlencodeData(value string) string {
+	eq := strings.Index(value, "=")
+	switch {
+	case eq < 0:
+		return url.QueryEscape(value)
+	case eq == 0:
+		return url.QueryEscape(value[1:])
+	default:
+		return value[:eq] + "=" + url.QueryEscape(value[eq+1:])
+	}
+}
+
+func (curl *curlCommand) request() (*models.RequestDB, error) {
+	if curl.target == "" {
+		return nil, errors.New("url is required")
+	}
+	if !strings.Contains(curl.target, "://") {
+		curl.target = "http://" + curl.target
+	}
+	target, err := url.Parse(curl.target)
+	if err != nil {
+		return nil, err
+	}
+	if (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, errors.New("url must be http or https url")
+	}
+
+	var (
+		body   = strings.Join(curl.data, "&")
+		method = curl.method
+	)
+	if curl.get && len(curl.data) > 0 {
+		if target.RawQuery != "" {
+			target.RawQuery += "&"
+		}
+		target.RawQuery += body
+		body = ""
+	}
+	if method == "" {
+		switch {
+		case curl.head:
+			method = "HEAD"
+		case body != "":
+			method = "POST"
+		default:
+			method = "GET"
+		}
+	}
+	header := curl.header
+	if body != "" && header.Get("Content-Type") == "" {
+		header = append(header, models.HeaderField{Name: "Content-Type", Value: "application/x-www-form-urlencoded"})
+	}
+	if header.Get("Host") == "" {
+		header = append(models.Header{{Name: "Host", Value: target.Host}}, header...)
+	}
+
+	var rdb = &models.RequestDB{
+		Method:       method,
+		Scheme:       target.Scheme,
+		RemoteAddr:   target.Host,
+		Path:         target.EscapedPath(),
+		RawQuery:     target.RawQuery,
+		Proto:        curl.proto,
+		Header:       header,
+		Body:         []byte(body),
+		UserLogin:    curl.login,
+		UserPassword: curl.password,
+	}
+	if target.User != nil && rdb.UserLogin == "" {
+		rdb.UserLogin = target.User.Username()
+		rdb.UserPassword, _ = target.User.Password()
+	}
+	target.User = nil
+	target.Fragment = ""
+	rdb.URL = target.String()
+	if rdb.Proto == "" {
+		rdb.Proto = "HTTP/1.1"
+	}
+	return rdb, nil
+}
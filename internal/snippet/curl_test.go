@@ -0,0 +1,196 @@
+package snippet
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+func TestShellQuoteParsed(t *testing.T) {
+	for _, s := range []string{"", "abc", "it's", "a\nb", "a\r\nb", "\x00\x01'\\", "a\xffb", `$HOME "x"`} {
+		commands, err := shellCommands("echo " + shellQuote(s))
+		if err != nil {
+			t.Fatalf("shellCommands of %q: %v", s, err)
+		}
+		if len(commands) != 1 || len(commands[0]) != 2 || commands[0][1] != s {
+			t.Errorf("quoted %q is parsed as %q", s, commands)
+		}
+	}
+}
+
+func TestPrintfBodyParsed(t *testing.T) {
+	for _, body := range [][]byte{nil, []byte("a=b"), []byte("100%"), []byte(`\'`), []byte("\x00\n"), {0xff, 0x7f}} {
+		commands, err := shellCommands(printfBody(body))
+		if err != nil {
+			t.Fatalf("shellCommands of printf of %q: %v", body, err)
+		}
+		if written := printfFormat(commands[0][1]); !bytes.Equal(written, body) {
+			t.Errorf("printf writes %q, want %q", written, body)
+		}
+	}
+}
+
+func TestParseCurl(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		method  string
+		url     string
+		body    string
+		header  models.Header
+		login   string
+	}{
+		{
+			name:    "get",
+			command: `curl 'http://example.com/a?b=c'`,
+			method:  "GET", url: "http://example.com/a?b=c",
+			header: models.Header{{Name: "Host", Value: "example.com"}},
+		},
+		{
+			name:    "combined flags with method",
+			command: `curl -sXPOST http://example.com/ -d 'a=b'`,
+			method:  "POST", url: "http://example.com/", body: "a=b",
+			header: models.Header{{Name: "Host", Value: "example.com"},
+				{Name: "Content-Type", Value: "application/x-www-form-urlencoded"}},
+		},
+		{
+			name:    "combined flags and method apart",
+			command: `curl -sSLX PUT http://example.com/`,
+			method:  "PUT", url: "http://example.com/",
+			header: models.Header{{Name: "Host", Value: "example.com"}},
+		},
+		{
+			name:    "inline data keeps line breaks",
+			command: `curl http://example.com/ -H 'Content-Type: text/plain' -d $'a\r\nb'`,
+			method:  "POST", url: "http://example.com/", body: "a\r\nb",
+			header: models.Header{{Name: "Host", Value: "example.com"},
+				{Name: "Content-Type", Value: "text/plain"}},
+		},
+		{
+			name:    "data from stdin drops line breaks",
+			command: `printf 'a\nb' | curl http://example.com/ -H 'Content-Type: text/plain' -d @-`,
+			method:  "POST", url: "http://example.com/", body: "ab",
+			header: models.Header{{Name: "Host", Value: "example.com"},
+				{Name: "Content-Type", Value: "text/plain"}},
+		},
+		{
+			name:    "binary data from stdin is kept",
+			command: `printf 'a\nb' | curl http://example.com/ -H 'Content-Type: text/plain' --data-binary @-`,
+			method:  "POST", url: "http://example.com/", body: "a\nb",
+			header: models.Header{{Name: "Host", Value: "example.com"},
+				{Name: "Content-Type", Value: "text/plain"}},
+		},
+		{
+			name:    "several data and user",
+			command: `curl -u 'user:pass' http://example.com/ -d a=1 --data-raw @b=2`,
+			method:  "POST", url: "http://example.com/", body: "a=1&@b=2",
+			header: models.Header{{Name: "Host", Value: "example.com"},
+				{Name: "Content-Type", Value: "application/x-www-form-urlencoded"}},
+			login: "user",
+		},
+		{
+			name:    "get with data",
+			command: `curl -G http://example.com/a?x=1 -d y=2`,
+			method:  "GET", url: "http://example.com/a?x=1&y=2",
+			header: models.Header{{Name: "Host", Value: "example.com"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rdb, err := ParseCurl(test.command)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rdb.Method != test.method || rdb.URL != test.url || string(rdb.Body) != test.body {
+				t.Errorf("got %s %s %q, want %s %s %q", rdb.Method, rdb.URL, rdb.Body,
+					test.method, test.url, test.body)
+			}
+			if !equalHeader(rdb.Header, test.header) {
+				t.Errorf("header %v, want %v", rdb.Header, test.header)
+			}
+			if rdb.UserLogin != test.login {
+				t.Errorf("login %q, want %q", rdb.UserLogin, test.login)
+			}
+		})
+	}
+}
+
+func TestParseCurlErrors(t *testing.T) {
+	for _, command := range []string{
+		`wget http://example.com/`,
+		`curl`,
+		`curl -d @body.txt http://example.com/`,
+		`curl -X`,
+		`curl --unknown http://example.com/`,
+		`curl ftp://example.com/`,
+	} {
+		if _, err := ParseCurl(command); err == nil {
+			t.Errorf("ParseCurl(%q) has no error", command)
+		}
+	}
+}
+
+// TestCurlRoundTrip render request as curl command and parse it back
+
+func TestCurlRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		body   []byte
+		header models.Header
+	}{
+		{"get", "GET", nil, nil},
+		{"post form", "POST", []byte("a=1&b=%20"), models.Header{{Name: "Content-Type", Value: "application/x-www-form-urlencoded"}}},
+		{"put json", "PUT", []byte(`{"a": "it's"}`), models.Header{{Name: "Content-Type", Value: "application/json"}}},
+		{"multiline", "POST", []byte("a\r\nb\n"), models.Header{{Name: "Content-Type", Value: "text/plain"}}},
+		{"body starting with @", "POST", []byte("@a"), models.Header{{Name: "Content-Type", Value: "text/plain"}}},
+		{"binary", "POST", []byte("\x00\xff\r\n%'\\"), models.Header{{Name: "Content-Type", Value: "application/octet-stream"}}},
+		{"delete", "DELETE", nil, models.Header{{Name: "X-Empty"}, {Name: "x-Custom", Value: "a b"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			original := &models.RequestDB{
+				Method:       test.method,
+				URL:          "https://example.com:8443/a%20b?c=d",
+				Header:       append(models.Header{{Name: "Host", Value: "example.com:8443"}}, test.header...),
+				Body:         test.body,
+				UserLogin:    "user",
+				UserPassword: "pa:ss",
+			}
+			command, err := Render(Curl, original)
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed, err := ParseCurl(command)
+			if err != nil {
+				t.Fatalf("ParseCurl(%q): %v", command, err)
+			}
+			if parsed.Method != original.Method || parsed.URL != original.URL {
+				t.Errorf("got %s %s, want %s %s", parsed.Method, parsed.URL, original.Method, original.URL)
+			}
+			if !bytes.Equal(parsed.Body, original.Body) && len(parsed.Body)+len(original.Body) > 0 {
+				t.Errorf("body %q, want %q", parsed.Body, original.Body)
+			}
+			if !equalHeader(parsed.Header, original.Header) {
+				t.Errorf("header %v, want %v", parsed.Header, original.Header)
+			}
+			if parsed.UserLogin != original.UserLogin || parsed.UserPassword != original.UserPassword {
+				t.Errorf("user %q:%q, want %q:%q", parsed.UserLogin, parsed.UserPassword,
+					original.UserLogin, original.UserPassword)
+			}
+		})
+	}
+}
+
+func equalHeader(a, b models.Header) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
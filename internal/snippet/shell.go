@@ -0,0 +1,349 @@
+package snippet
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// lineBreak - continuation of long command on the next line
+const lineBreak = " \\\n  "
+
+func renderCurl(r *request) string {
+	var args = []string{"curl"}
+	switch {
+	case r.method == "HEAD":
+		args = append(args, "-I")
+	case r.method == "GET" && len(r.body) == 0:
+	case r.method == "POST" && len(r.body) > 0:
+	default:
+		args = append(args, "-X "+shellQuote(r.method))
+	}
+	args = append(args, shellQuote(r.url))
+	for _, field := range r.header {
+		if field.Value == "" {
+			// curl removes header with empty value, "Name;" sends it
+			args = append(args, "-H "+shellQuote(field.Name+";"))
+		} else {
+			args = append(args, "-H "+shellQuote(field.Name+": "+field.Value))
+		}
+	}
+	if r.header.Get("Accept-Encoding") != "" {
+		args = append(args, "--compressed")
+	}
+	if r.login != "" {
+		args = append(args, "-u "+shellQuote(r.login+":"+r.password))
+	}
+
+	var pipe string
+	if len(r.body) > 0 {
+		if r.binary() || r.hasZero() {
+			pipe = printfBody(r.body) + " | "
+			args = append(args, "--data-binary @-")
+		} else {
+			// --data-binary reads file, if the body starts with @
+			args = append(args, "--data-raw "+shellQuote(string(r.body)))
+		}
+	}
+	return pipe + strings.Join(args, lineBreak) + "\n"
+}
+
+func renderWget(r *request) string {
+	var args = []string{"wget", "--method=" + shellQuote(r.method)}
+	for _, field := range r.header {
+		args = append(args, "--header="+shellQuote(field.Name+": "+field.Value))
+	}
+	if r.login != "" {
+		args = append(args, "--auth-no-challenge --user="+shellQuote(r.login)+
+			" --password="+shellQuote(r.password))
+	}
+
+	var file string
+	if len(r.body) > 0 {
+		if r.hasZero() {
+			// wget can not read body from pipe
+			file = printfBody(r.body) + " > body.bin && "
+			args = append(args, "--body-file=body.bin")
+		} else {
+			args = append(args, "--body-data="+shellQuote(string(r.body)))
+		}
+	}
+	args = append(args, "-O - "+shellQuote(r.url))
+	return file + strings.Join(args, lineBreak) + "\n"
+}
+
+func renderHTTPie(r *request) string {
+	var (
+		args = []string{"http"}
+		pipe string
+	)
+	if len(r.body) > 0 {
+		if r.hasZero() {
+			pipe = printfBody(r.body) + " | "
+		} else {
+			args = append(args, "--raw "+shellQuote(string(r.body)))
+		}
+	}
+	if r.login != "" {
+		args = append(args, "-a "+shellQuote(r.login+":"+r.password))
+	}
+	args = append(args, shellQuote(r.method)+" "+shellQuote(r.url))
+	for _, field := range r.header {
+		if field.Value == "" {
+			// httpie sends header with empty value as "Name;"
+			args = append(args, shellQuote(field.Name+";"))
+		} else {
+			args = append(args, shellQuote(field.Name+":"+field.Value))
+		}
+	}
+	return pipe + strings.Join(args, lineBreak) + "\n"
+}
+
+// shellQuote quote s for POSIX shell. Strings with control symbols
+// or invalid utf-8 are quoted as $'...' with escapes, as in bash
+func shellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	if !needsEscapes(s) {
+		return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+	}
+
+	var quoted strings.Builder
+	quoted.WriteString("$'")
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == '\\' || r == '\'':
+			quoted.WriteString(`\` + string(r))
+		case r == '\n':
+			quoted.WriteString(`\n`)
+		case r == '\r':
+			quoted.WriteString(`\r`)
+		case r == '\t':
+			quoted.WriteString(`\t`)
+		case r == utf8.RuneError && size == 1, r < 0x20, r == 0x7f:
+			fmt.Fprintf(&quoted, `\x%02x`, s[i])
+		default:
+			quoted.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	quoted.WriteString("'")
+	return quoted.String()
+}
+
+func needsEscapes(s string) bool {
+	if !utf8.ValidString(s) {
+		return true
+	}
+	for _, r := range s {
+		if (r < 0x20 && r != '\n' && r != '\t') || r == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+// printfBody make printf command writing body. Any bytes,
+// even zero, can be written this way
+func printfBody(body []byte) string {
+	var format strings.Builder
+	for _, b := range body {
+		switch {
+		case b == '%':
+			format.WriteString("%%")
+		case b == '\\':
+			format.WriteString(`\\`)
+		case b == '\'':
+			format.WriteString(`'\''`)
+		case b >= 0x20 && b < 0x7f:
+			format.WriteByte(b)
+		default:
+			fmt.Fprintf(&format, `\%03o`, b)
+		}
+	}
+	return "printf '" + format.String() + "'"
+}
+
+// shellCommands split command line to commands of pipelines and
+// lists and commands to words, undoing POSIX shell and bash quoting
+func shellCommands(line string) ([][]string, error) {
+	var (
+		commands [][]string
+		words    []string
+		word     strings.Builder
+		inWord   bool
+	)
+	endWord := func() {
+		if inWord {
+			words = append(words, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+	endCommand := func() {
+		endWord()
+		commands = append(commands, words)
+		words = nil
+	}
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '\\':
+			if i+1 < len(line) {
+				i++
+				// backslash and line break continue the line
+				if line[i] != '\n' {
+					word.WriteByte(line[i])
+					inWord = true
+				}
+			}
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			endWord()
+		case c == '|' || c == ';':
+			endCommand()
+		case c == '&' && i+1 < len(line) && line[i+1] == '&':
+			i++
+			endCommand()
+		case c == '\'':
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated quote")
+			}
+			word.WriteString(line[i+1 : i+1+end])
+			inWord = true
+			i += end + 1
+		case c == '$' && i+1 < len(line) && line[i+1] == '\'':
+			end, err := ansiQuoted(line[i+2:], &word)
+			if err != nil {
+				return nil, err
+			}
+			inWord = true
+			i += end + 2
+		case c == '"':
+			end, err := doubleQuoted(line[i+1:], &word)
+			if err != nil {
+				return nil, err
+			}
+			inWord = true
+			i += end + 1
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	endCommand()
+	return commands, nil
+}
+
+// doubleQuoted read "..." string without the first quote to word
+// and return position of the closing quote
+func doubleQuoted(s string, word *strings.Builder) (int, error) {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return i, nil
+		case '\\':
+			if i+1 < len(s) && strings.IndexByte("$`\"\\\n", s[i+1]) >= 0 {
+				i++
+				if s[i] != '\n' {
+					word.WriteByte(s[i])
+				}
+				continue
+			}
+		}
+		word.WriteByte(s[i])
+	}
+	return 0, errors.New("unterminated double quote")
+}
+
+// ansiQuoted read $'...' string without $' to word
+// and return position of the closing quote
+func ansiQuoted(s string, word *strings.Builder) (int, error) {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\'' {
+			return i, nil
+		}
+		if s[i] != '\\' || i+1 >= len(s) {
+			word.WriteByte(s[i])
+			continue
+		}
+		n := unescape(s[i+1:], word)
+		i += n
+	}
+	return 0, errors.New("unterminated quote")
+}
+
+// printfFormat return what printf writes for format without arguments
+func printfFormat(format string) []byte {
+	var out strings.Builder
+	for i := 0; i < len(format); i++ {
+		switch {
+		case format[i] == '%' && i+1 < len(format) && format[i+1] == '%':
+			out.WriteByte('%')
+			i++
+		case format[i] == '\\' && i+1 < len(format):
+			i += unescape(format[i+1:], &out)
+		default:
+			out.WriteByte(format[i])
+		}
+	}
+	return []byte(out.String())
+}
+
+// escapes - one letter escape sequences of bash and printf
+var escapes = map[byte]byte{
+	'n': '\n', 'r': '\r', 't': '\t', 'a': '\a', 'b': '\b',
+	'f': '\f', 'v': '\v', 'e': 0x1b, '\\': '\\', '\'': '\'', '"': '"',
+}
+
+// unescape write the symbol of escape sequence s, that follows
+// backslash, and return the length of sequence
+func unescape(s string, out *strings.Builder) int {
+	if c, ok := escapes[s[0]]; ok {
+		out.WriteByte(c)
+		return 1
+	}
+	switch {
+	case s[0] == 'x':
+		n, value := 0, 0
+		for n < 2 && 1+n < len(s) && isHex(s[1+n]) {
+			value = value*16 + hexValue(s[1+n])
+			n++
+		}
+		if n == 0 {
+			out.WriteString(`\x`)
+			return 1
+		}
+		out.WriteByte(byte(value))
+		return 1 + n
+	case s[0] >= '0' && s[0] <= '7':
+		n, value := 0, 0
+		for n < 3 && n < len(s) && s[n] >= '0' && s[n] <= '7' {
+			value = value*8 + int(s[n]-'0')
+			n++
+		}
+		out.WriteByte(byte(value))
+		return n
+	}
+	out.WriteByte('\\')
+	out.WriteByte(s[0])
+	return 1
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func hexValue(c byte) int {
+	switch {
+	case c >= 'a':
+		return int(c-'a') + 10
+	case c >= 'A':
+		return int(c-'A') + 10
+	}
+	return int(c - '0')
+}
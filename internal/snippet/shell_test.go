@@ -0,0 +1,76 @@
+package snippet
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{"empty", "", `''`},
+		{"plain", "abc", `'abc'`},
+		{"single quote", "it's", `'it'\''s'`},
+		{"line break is kept", "a\nb", "'a\nb'"},
+		{"carriage return", "a\r\nb", `$'a\r\nb'`},
+		{"control symbol", "a\x01b", `$'a\x01b'`},
+		{"escapes in ansi quotes", "\x01'\\", `$'\x01\'\\'`},
+		{"invalid utf-8", "a\xffb", `$'a\xffb'`},
+		{"utf-8", "привет", `'привет'`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := shellQuote(test.s); got != test.want {
+				t.Errorf("shellQuote(%q) = %s, want %s", test.s, got, test.want)
+			}
+		})
+	}
+}
+
+func TestPrintfBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body []byte
+		want string
+	}{
+		{"text", []byte("a=b"), `printf 'a=b'`},
+		{"percent", []byte("100%"), `printf '100%%'`},
+		{"backslash and quote", []byte(`\'`), `printf '\\'\'''`},
+		{"zero and line break", []byte("\x00\n"), `printf '\000\012'`},
+		{"binary", []byte{0xff, 0x7f}, `printf '\377\177'`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := printfBody(test.body); got != test.want {
+				t.Errorf("printfBody(%q) = %s, want %s", test.body, got, test.want)
+			}
+		})
+	}
+}
+
+func TestRenderCurlBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body []byte
+		want []string
+	}{
+		{"text", []byte("a=b"), []string{"--data-raw 'a=b'"}},
+		{"text starting with @", []byte("@file"), []string{"--data-raw '@file'"}},
+		{"multiline text", []byte("a\nb"), []string{"--data-raw 'a\nb'"}},
+		{"zero byte", []byte("a\x00b"), []string{`printf 'a\000b' | `, "--data-binary @-"}},
+		{"invalid utf-8", []byte{0xff}, []string{`printf '\377' | `, "--data-binary @-"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := renderCurl(&request{method: "POST", url: "http://example.com/", body: test.body})
+			for _, want := range test.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("curl command %q has no %q", got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+// Package snippet renders stored requests as commands and code,
+// that send the same request
+package snippet
+
+import (
+	"bytes"
+	"errors"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/models"
+)
+
+// formats of snippets
+const (
+	Curl           = "curl"
+	Wget           = "wget"
+	HTTPie         = "httpie"
+	PythonRequests = "python-requests"
+	Go             = "go"
+	PowerShell     = "powershell"
+)
+
+var renderers = map[string]func(*request) string{
+	Curl:           renderCurl,
+	Wget:           renderWget,
+	HTTPie:         renderHTTPie,
+	PythonRequests: renderPython,
+	Go:             renderGo,
+	PowerShell:     renderPowerShell,
+}
+
+// skippedHeaders - headers, that are set by tools themselves
+// or belong to the connection with proxy
+var skippedHeaders = []string{
+	"Content-Length",
+	"Transfer-Encoding",
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Connection",
+	"Proxy-Authorization",
+}
+
+// request - what is rendered
+type request struct {
+	method string
+	url    string
+	header models.Header
+	body   []byte
+	// login and password of basic authorization, if login is not empty
+	login, password string
+}
+
+// Render make snippet of format, that sends rdb
+func Render(format string, rdb *models.RequestDB) (string, error) {
+	render, ok := renderers[format]
+	if !ok {
+		return "", errors.New("format must be one of curl, wget, httpie, python-requests, go, powershell")
+	}
+	return render(newRequest(rdb)), nil
+}
+
+func newRequest(rdb *models.RequestDB) *request {
+	var r = &request{
+		method:   rdb.Method,
+		url:      rdb.TargetURL(),
+		header:   rdb.Header,
+		body:     rdb.Body,
+		login:    rdb.UserLogin,
+		password: rdb.UserPassword,
+	}
+	if r.method == "" {
+		r.method = "GET"
+	}
+	for _, name := range skippedHeaders {
+		r.header = r.header.Del(name)
+	}
+	// Host is needed only if it differs from the host of url
+	if target, err := url.Parse(r.url); err == nil && strings.EqualFold(r.header.Get("Host"), target.Host) {
+		r.header = r.header.Del("Host")
+	}
+	return r
+}
+
+// binary check if body is not utf-8 text
+func (r *request) binary() bool {
+	return !utf8.Valid(r.body)
+}
+
+// hasZero check if body has zero bytes, that can not
+// be passed in arguments of command
+func (r *request) hasZero() bool {
+	return bytes.IndexByte(r.body, 0) >= 0
+}
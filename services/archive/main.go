@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/archive"
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/database"
+)
+
+// export project to archive or import archive as new project.
+// The store is set by the same PROXY_STORE variables as for services
+func main() {
+	var (
+		export   = flag.Int("export", 0, "id of project to export")
+		doImport = flag.Bool("import", false, "import archive as new project")
+		file     = flag.String("file", "", "archive file, stdout on export and stdin on import by default")
+		name     = flag.String("name", "", "name of imported project instead of the name from archive")
+		rules    = flag.Bool("rules", true, "import rules of archive, -rules=false to skip them")
+	)
+	flag.Parse()
+	if (*export > 0) == *doImport {
+		log.Fatal("set -export <project id> or -import")
+	}
+
+	config := database.ConfigFromEnv()
+	if config.Kind == database.StoreMemory {
+		log.Fatal("memory store is empty in a new process, set PROXY_STORE to postgres or bolt")
+	}
+	db, err := database.Open(config)
+	if err != nil {
+		log.Fatal("cant open store: ", err)
+	}
+
+	if *doImport {
+		err = importArchive(db, *file, archive.Options{Name: *name, SkipRules: !*rules})
+	} else {
+		err = exportArchive(db, int32(*export), *file)
+	}
+	if closeErr := db.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("cant close store: %v", closeErr)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// importArchive import archive from file or stdin as new project
+func importArchive(db database.Store, file string, options archive.Options) error {
+	var r io.Reader = os.Stdin
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	summary, err := archive.Import(r, db, options)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported project %d %q: %d requests, %d responses, %d websocket messages, %d rules\n",
+		summary.Project.ID, summary.Project.Name, summary.Requests,
+		summary.Responses, summary.Messages, summary.Rules)
+	return nil
+}
+
+// exportArchive export project with id to file or stdout.
+// The file is removed, if the project could not be exported
+func exportArchive(db database.Store, id int32, file string) error {
+	project, err := db.GetProject(id)
+	if err != nil {
+		return fmt.Errorf("cant find project: %v", err)
+	}
+	if file == "" {
+		return archive.Export(os.Stdout, db, project)
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	err = archive.Export(f, db, project)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file)
+	}
+	return err
+}
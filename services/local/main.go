@@ -0,0 +1,21 @@
+package main
+
+import (
+	"log"
+	"runtime"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/repeater"
+)
+
+// proxy and repeater in one process, so that they can share
+// bolt store, which can not be opened by two processes
+func main() {
+	runtime.GOMAXPROCS(runtime.NumCPU())
+	server, err := repeater.Init()
+	if err != nil {
+		log.Fatal("cant launch Proxy")
+	}
+	defer server.Close()
+	go server.Proxy().Run()
+	server.Run()
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/SmartPhoneJava/SecurityProxyServer/internal/database"
+)
+
+// apply pending migrations to postgres or, with -dry-run,
+// list them. Services apply them on start themselves
+func main() {
+	dryRun := flag.Bool("dry-run", false, "list pending migrations without applying them")
+	flag.Parse()
+
+	config := database.ConfigFromEnv()
+	if config.Kind != database.StorePostgres {
+		log.Fatal("migrations are needed only for postgres store")
+	}
+	db, err := database.Init(config.Postgres)
+	if err != nil {
+		log.Fatal("cant connect to database: ", err)
+	}
+	defer db.Close()
+
+	migrations, err := db.Migrate(*dryRun)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(migrations) == 0 {
+		fmt.Println("No pending migrations")
+		return
+	}
+	for _, migration := range migrations {
+		if *dryRun {
+			fmt.Printf("Pending %d: %s\n", migration.Version, migration.Name)
+		} else {
+			fmt.Printf("Applied %d: %s\n", migration.Version, migration.Name)
+		}
+	}
+}